@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,21 +26,132 @@ import (
 
 // BackupsSpec defines the desired state of Backups
 type BackupsSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// StorageLocation is the name of the StorageLocations object in the same namespace
+	// which the backup is uploaded to, the default storage location is used if it is empty.
+	StorageLocation string `json:"storageLocation,omitempty"`
+	// ReplicaStorageLocations are further StorageLocations objects in the same namespace which the backup is
+	// replicated to, e.g. a secondary bucket for disaster recovery. A storage location which is added
+	// after the backup has finished gets a copy of the backup from StorageLocation. Replicas hold the backup
+	// tree only, the backup log is kept in StorageLocation.
+	ReplicaStorageLocations []string `json:"replicaStorageLocations,omitempty"`
+	// TreeName is the name of the remote backup tree, the name of the Backups object is used if it is empty.
+	TreeName string `json:"treeName,omitempty"`
+	// TTL is how long the backup is kept after it has finished, the backup is kept forever if it is empty.
+	// Expired backups are removed from the storage location together with their Backups object.
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// DefaultFilters overrides the default exclusions of the controller for this backup,
+	// e.g. to back up pods or to skip persistentvolumes.
+	DefaultFilters *DefaultFiltersSpec `json:"defaultFilters,omitempty"`
 
-	// Foo is an example field of Backups. Edit backups_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	ResourceFilterSpec `json:",inline"`
+}
+
+// DefaultFiltersSpec replaces the default exclusions which are configured for the controller. A list which is
+// left out keeps the configured one and an empty list clears it, so the lists are not omitted when they are empty.
+type DefaultFiltersSpec struct {
+	// ExcludedNamespaces are never backed up, kube-system, kube-public and kube-node-lease unless configured otherwise.
+	// +optional
+	ExcludedNamespaces []string `json:"excludedNamespaces"`
+	// ExcludedResources are never backed up, events, events.events.k8s.io, pods and endpointslices
+	// unless configured otherwise.
+	// +optional
+	ExcludedResources []string `json:"excludedResources"`
+	// IncludedClusterResources are backed up although includeClusterResources is not set,
+	// namespaces and persistentvolumes unless configured otherwise.
+	// +optional
+	IncludedClusterResources []string `json:"includedClusterResources"`
+	// ExcludedObjects are the names of the objects which are never backed up, keyed by resource.group or by the
+	// bare resource for the core group, secrets named default-token-* unless configured otherwise.
+	// +optional
+	ExcludedObjects map[string][]string `json:"excludedObjects"`
+}
+
+// ResourceFilterSpec selects which part of the cluster is handled by a backup or restore.
+// Namespaces and resources are given by name, by glob pattern such as team-* or by regex between slashes
+// such as /team-(a|b)/, a pattern has to match the whole name. Resources follow the kubectl notation
+// resource.group or resource.version.group, e.g. events.events.k8s.io or deployments.v1.apps, a bare
+// resource name refers to the core group resource of that name if there is one.
+type ResourceFilterSpec struct {
+	IncludedNamespaces      []string `json:"includedNamespaces,omitempty"`
+	ExcludedNamespaces      []string `json:"excludedNamespaces,omitempty"`
+	IncludedResources       []string `json:"includedResources,omitempty"`
+	ExcludedResources       []string `json:"excludedResources,omitempty"`
+	IncludeClusterResources bool     `json:"includeClusterResources,omitempty"`
+	// LabelSelector only selects the objects whose labels match it, in every selected namespace and resource.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// ObjectFilters include or exclude the objects of single resources by name. Objects annotated with
+	// boxroom.io/exclude-from-backup=true are never backed up.
+	ObjectFilters []ObjectFilter `json:"objectFilters,omitempty"`
+}
+
+// ObjectFilter includes or excludes the objects of one resource by name or pattern
+type ObjectFilter struct {
+	// Resource is the resource.group whose objects are filtered, e.g. deployments.apps, the resources of the
+	// core group are given by their bare name, e.g. secrets. The group/resource notation is still accepted.
+	Resource      string   `json:"resource"`
+	IncludedNames []string `json:"includedNames,omitempty"`
+	ExcludedNames []string `json:"excludedNames,omitempty"`
+}
+
+// BackupPhase is the lifecycle phase of a Backups object
+type BackupPhase string
+
+const (
+	BackupPhaseNew             BackupPhase = "New"
+	BackupPhaseInProgress      BackupPhase = "InProgress"
+	BackupPhaseCompleted       BackupPhase = "Completed"
+	BackupPhasePartiallyFailed BackupPhase = "PartiallyFailed"
+	BackupPhaseFailed          BackupPhase = "Failed"
+)
+
+const (
+	// BackupConditionCompleted is true once a backup has finished, whatever its result is.
+	BackupConditionCompleted = "Completed"
+	// BackupConditionSucceeded tells whether a finished backup has backed up every object.
+	BackupConditionSucceeded = "Succeeded"
+	// BackupConditionOrphaned is true once the storage location of the backup has been deleted with the Orphan policy.
+	BackupConditionOrphaned = "Orphaned"
+)
+
+// BackupStorageLocationStatus is the state of a backup in one of the storage locations it is written to
+type BackupStorageLocationStatus struct {
+	StorageLocationReference `json:",inline"`
+	// Primary marks the storage location which the backup is restored from and which keeps the backup log.
+	Primary bool `json:"primary,omitempty"`
+	// Phase is Completed once the backup tree is written to the storage location, or Failed.
+	Phase               BackupPhase  `json:"phase"`
+	Message             string       `json:"message,omitempty"`
+	CompletionTimestamp *metav1.Time `json:"completionTimestamp,omitempty"`
 }
 
 // BackupsStatus defines the observed state of Backups
 type BackupsStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	Phase               BackupPhase  `json:"phase,omitempty"`
+	StartTimestamp      *metav1.Time `json:"startTimestamp,omitempty"`
+	CompletionTimestamp *metav1.Time `json:"completionTimestamp,omitempty"`
+	// Expiration is the time after which the backup is garbage collected.
+	Expiration *metav1.Time `json:"expiration,omitempty"`
+	// StorageLocation is the resolved storage location which the backup is uploaded to.
+	StorageLocation *StorageLocationReference `json:"storageLocation,omitempty"`
+	// StorageLocations has an entry for the storage location and for each replica storage location of the backup.
+	StorageLocations []BackupStorageLocationStatus `json:"storageLocations,omitempty"`
+	// TreeName is the resolved name of the remote backup tree.
+	TreeName      string `json:"treeName,omitempty"`
+	ItemsBackedUp int    `json:"itemsBackedUp,omitempty"`
+	ItemsFailed   int    `json:"itemsFailed,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Tree",type=string,JSONPath=`.status.treeName`
+//+kubebuilder:printcolumn:name="Backed Up",type=integer,JSONPath=`.status.itemsBackedUp`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.itemsFailed`
+//+kubebuilder:printcolumn:name="Storage Location",type=string,JSONPath=`.status.storageLocation.name`
+//+kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expiration`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Backups is the Schema for the backups API
 type Backups struct {
@@ -59,6 +171,83 @@ type BackupsList struct {
 	Items           []Backups `json:"items"`
 }
 
+// GetStorageLocationReference is the resolved storage location of the backup, a backup which has not been
+// resolved yet refers to the storage location named by its spec.
+func (backup *Backups) GetStorageLocationReference() StorageLocationReference {
+	if backup.Status.StorageLocation != nil {
+		return *backup.Status.StorageLocation
+	}
+	return StorageLocationReference{Namespace: backup.Namespace, Name: backup.Spec.StorageLocation}
+}
+
+// GetStorageLocationStatus finds the entry of a storage location in the status, it is nil
+// for a storage location which the backup has not been written to yet.
+func (backup *Backups) GetStorageLocationStatus(ref StorageLocationReference) *BackupStorageLocationStatus {
+	for i := range backup.Status.StorageLocations {
+		if backup.Status.StorageLocations[i].StorageLocationReference == ref {
+			return &backup.Status.StorageLocations[i]
+		}
+	}
+	return nil
+}
+
+// SetStorageLocationStatus adds or replaces the entry of a storage location in the status.
+func (backup *Backups) SetStorageLocationStatus(locationStatus BackupStorageLocationStatus) {
+	if existing := backup.GetStorageLocationStatus(locationStatus.StorageLocationReference); existing != nil {
+		*existing = locationStatus
+		return
+	}
+	backup.Status.StorageLocations = append(backup.Status.StorageLocations, locationStatus)
+}
+
+// GetPendingReplicaStorageLocations lists the replica storage locations which the backup has not been written to yet.
+func (backup *Backups) GetPendingReplicaStorageLocations() []StorageLocationReference {
+	var pending []StorageLocationReference
+	for _, name := range backup.Spec.ReplicaStorageLocations {
+		ref := StorageLocationReference{Namespace: backup.Namespace, Name: name}
+		if ref != backup.GetStorageLocationReference() && backup.GetStorageLocationStatus(ref) == nil {
+			pending = append(pending, ref)
+		}
+	}
+	return pending
+}
+
+// RefersToStorageLocation tells whether the backup tree is kept in the storage location, or is about to be written to it.
+// The tree of an orphaned backup and the tree of a failed replica are not kept in their storage location.
+func (backup *Backups) RefersToStorageLocation(ref StorageLocationReference) bool {
+	if backup.GetStorageLocationReference() == ref {
+		return !backup.IsOrphaned()
+	}
+	if locationStatus := backup.GetStorageLocationStatus(ref); locationStatus != nil {
+		return locationStatus.Phase == BackupPhaseCompleted
+	}
+	for _, pending := range backup.GetPendingReplicaStorageLocations() {
+		if pending == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinished tells whether the backup has reached one of its final phases.
+func (backup *Backups) IsFinished() bool {
+	switch backup.Status.Phase {
+	case BackupPhaseCompleted, BackupPhasePartiallyFailed, BackupPhaseFailed:
+		return true
+	}
+	return false
+}
+
+// IsRestorable tells whether the backup has uploaded a tree which can be restored.
+func (backup *Backups) IsRestorable() bool {
+	return backup.Status.Phase == BackupPhaseCompleted || backup.Status.Phase == BackupPhasePartiallyFailed
+}
+
+// IsOrphaned tells whether the storage location of the backup has been deleted and the backup tree can not be reached.
+func (backup *Backups) IsOrphaned() bool {
+	return meta.IsStatusConditionTrue(backup.Status.Conditions, BackupConditionOrphaned)
+}
+
 func init() {
 	SchemeBuilder.Register(&Backups{}, &BackupsList{})
 }
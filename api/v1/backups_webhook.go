@@ -0,0 +1,264 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	k8sfilter "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-filter"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func (backup *Backups) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(backup).
+		WithValidator(&backupsValidator{client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-boxroom-io-v1-backups,mutating=false,failurePolicy=fail,sideEffects=None,groups=boxroom.io,resources=backups,verbs=create;update,versions=v1,name=vbackups.kb.io,admissionReviewVersions=v1
+
+// backupsValidator needs a client to look up the storage location a backup refers to.
+type backupsValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &backupsValidator{}
+
+func (v *backupsValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	backup := obj.(*Backups)
+	specPath := field.NewPath("spec")
+
+	errs := backup.Spec.ResourceFilterSpec.validate(specPath)
+	if backup.Spec.DefaultFilters != nil {
+		errs = append(errs, backup.Spec.DefaultFilters.validate(specPath.Child("defaultFilters"))...)
+	}
+	errs = append(errs, validateTreeName(specPath.Child("treeName"), backup.Spec.TreeName)...)
+	if backup.Spec.TTL != nil && backup.Spec.TTL.Duration < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("ttl"), backup.Spec.TTL.Duration.String(), "must not be negative"))
+	}
+	errs = append(errs, validateStorageLocation(ctx, v.client, specPath.Child("storageLocation"), backup.Namespace, backup.Spec.StorageLocation)...)
+	errs = append(errs, v.validateReplicaStorageLocations(ctx, specPath.Child("replicaStorageLocations"), backup, nil)...)
+
+	if len(errs) != 0 {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Backups").GroupKind(), backup.Name, errs)
+	}
+	return nil, nil
+}
+
+// ValidateUpdate only lets the ttl change and replica storage locations be added,
+// the rest of the spec describes a backup which may already be taken.
+func (v *backupsValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldBackup, backup := oldObj.(*Backups), newObj.(*Backups)
+	specPath := field.NewPath("spec")
+
+	oldSpec := oldBackup.Spec.DeepCopy()
+	oldSpec.TTL = backup.Spec.TTL
+	oldSpec.ReplicaStorageLocations = backup.Spec.ReplicaStorageLocations
+	if !reflect.DeepEqual(*oldSpec, backup.Spec) {
+		errs := field.ErrorList{field.Forbidden(specPath, "only spec.ttl and spec.replicaStorageLocations can be changed")}
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Backups").GroupKind(), backup.Name, errs)
+	}
+
+	var errs field.ErrorList
+	if backup.Spec.TTL != nil && backup.Spec.TTL.Duration < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("ttl"), backup.Spec.TTL.Duration.String(), "must not be negative"))
+	}
+	errs = append(errs, v.validateReplicaStorageLocations(ctx, specPath.Child("replicaStorageLocations"), backup, oldBackup.Spec.ReplicaStorageLocations)...)
+	if len(errs) != 0 {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Backups").GroupKind(), backup.Name, errs)
+	}
+	return nil, nil
+}
+
+// validateReplicaStorageLocations makes sure the replica storage locations are distinct from each other and from
+// the storage location of the backup. The replicas which already exist can not be removed, only the added ones
+// have to exist because a replica may have been deleted with the Orphan policy since.
+func (v *backupsValidator) validateReplicaStorageLocations(ctx context.Context, path *field.Path, backup *Backups, oldNames []string) field.ErrorList {
+	var errs field.ErrorList
+	names := map[string]bool{}
+	for i, name := range backup.Spec.ReplicaStorageLocations {
+		switch {
+		case len(name) == 0:
+			errs = append(errs, field.Required(path.Index(i), ""))
+		case names[name]:
+			errs = append(errs, field.Duplicate(path.Index(i), name))
+		case name == backup.Spec.StorageLocation || (backup.Status.StorageLocation != nil && *backup.Status.StorageLocation == StorageLocationReference{Namespace: backup.Namespace, Name: name}):
+			errs = append(errs, field.Invalid(path.Index(i), name, "must not be the storage location of the backup"))
+		}
+		names[name] = true
+	}
+
+	oldSet := map[string]bool{}
+	for _, name := range oldNames {
+		oldSet[name] = true
+		if !names[name] {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("replica storage location %s can not be removed", name)))
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
+	for i, name := range backup.Spec.ReplicaStorageLocations {
+		if !oldSet[name] {
+			errs = append(errs, validateStorageLocation(ctx, v.client, path.Index(i), backup.Namespace, name)...)
+		}
+	}
+	return errs
+}
+
+func (v *backupsValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the names of the filters, namespaces have to be dns labels and resources dns subdomains,
+// and a name can not be included and excluded at the same time. Glob patterns and regexes between slashes
+// are accepted in place of a name as long as they compile. The label selector has to be a valid selector,
+// and every resource has at most one object filter.
+func (spec *ResourceFilterSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateFilterNames(path.Child("includedNamespaces"), spec.IncludedNamespaces, validation.IsDNS1123Label)...)
+	errs = append(errs, validateFilterNames(path.Child("excludedNamespaces"), spec.ExcludedNamespaces, validation.IsDNS1123Label)...)
+	errs = append(errs, validateFilterNames(path.Child("includedResources"), spec.IncludedResources, validation.IsDNS1123Subdomain)...)
+	errs = append(errs, validateFilterNames(path.Child("excludedResources"), spec.ExcludedResources, validation.IsDNS1123Subdomain)...)
+	errs = append(errs, validateFilterOverlap(path.Child("excludedNamespaces"), spec.IncludedNamespaces, spec.ExcludedNamespaces)...)
+	errs = append(errs, validateFilterOverlap(path.Child("excludedResources"), spec.IncludedResources, spec.ExcludedResources)...)
+	errs = append(errs, metav1validation.ValidateLabelSelector(spec.LabelSelector, metav1validation.LabelSelectorValidationOptions{}, path.Child("labelSelector"))...)
+
+	resources := map[string]bool{}
+	for i, objectFilter := range spec.ObjectFilters {
+		filterPath := path.Child("objectFilters").Index(i)
+		errs = append(errs, validateGroupResource(filterPath.Child("resource"), objectFilter.Resource)...)
+		key := k8sfilter.NormalizeGroupResourceKey(objectFilter.Resource)
+		if resources[key] {
+			errs = append(errs, field.Duplicate(filterPath.Child("resource"), objectFilter.Resource))
+		}
+		resources[key] = true
+
+		if len(objectFilter.IncludedNames) == 0 && len(objectFilter.ExcludedNames) == 0 {
+			errs = append(errs, field.Required(filterPath.Child("includedNames"), "either includedNames or excludedNames must be set"))
+		}
+		errs = append(errs, validateFilterNames(filterPath.Child("includedNames"), objectFilter.IncludedNames, validation.IsDNS1123Subdomain)...)
+		errs = append(errs, validateFilterNames(filterPath.Child("excludedNames"), objectFilter.ExcludedNames, validation.IsDNS1123Subdomain)...)
+		errs = append(errs, validateFilterOverlap(filterPath.Child("excludedNames"), objectFilter.IncludedNames, objectFilter.ExcludedNames)...)
+	}
+	return errs
+}
+
+// validate checks the names of the default filters the same way as the names of the resource filters.
+func (spec *DefaultFiltersSpec) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateFilterNames(path.Child("excludedNamespaces"), spec.ExcludedNamespaces, validation.IsDNS1123Label)...)
+	errs = append(errs, validateFilterNames(path.Child("excludedResources"), spec.ExcludedResources, validation.IsDNS1123Subdomain)...)
+	errs = append(errs, validateFilterNames(path.Child("includedClusterResources"), spec.IncludedClusterResources, validation.IsDNS1123Subdomain)...)
+
+	excludedObjectsPath := path.Child("excludedObjects")
+	resources := map[string]bool{}
+	for groupResource, names := range spec.ExcludedObjects {
+		errs = append(errs, validateGroupResource(excludedObjectsPath, groupResource)...)
+		key := k8sfilter.NormalizeGroupResourceKey(groupResource)
+		if resources[key] {
+			errs = append(errs, field.Duplicate(excludedObjectsPath, groupResource))
+		}
+		resources[key] = true
+		errs = append(errs, validateFilterNames(excludedObjectsPath.Key(groupResource), names, validation.IsDNS1123Subdomain)...)
+	}
+	return errs
+}
+
+// validateGroupResource accepts resource.group, or the bare resource name of the core group. The group/resource
+// notation which the object filters were keyed by before is accepted as well.
+func validateGroupResource(path *field.Path, groupResource string) field.ErrorList {
+	resource, group, found := strings.Cut(k8sfilter.NormalizeGroupResourceKey(groupResource), ".")
+
+	var errs field.ErrorList
+	if found {
+		for _, msg := range validation.IsDNS1123Subdomain(group) {
+			errs = append(errs, field.Invalid(path, groupResource, "group: "+msg))
+		}
+	}
+	for _, msg := range validation.IsDNS1123Label(resource) {
+		errs = append(errs, field.Invalid(path, groupResource, "resource: "+msg))
+	}
+	return errs
+}
+
+func validateFilterNames(path *field.Path, names []string, validate func(string) []string) field.ErrorList {
+	var errs field.ErrorList
+	for i, name := range names {
+		if k8sfilter.IsRegexPattern(name) || k8sfilter.IsGlobPattern(name) {
+			if err := k8sfilter.ValidatePattern(name); err != nil {
+				errs = append(errs, field.Invalid(path.Index(i), name, err.Error()))
+			}
+			continue
+		}
+		for _, msg := range validate(name) {
+			errs = append(errs, field.Invalid(path.Index(i), name, msg))
+		}
+	}
+	return errs
+}
+
+func validateFilterOverlap(path *field.Path, included, excluded []string) field.ErrorList {
+	var errs field.ErrorList
+	for i, name := range excluded {
+		for _, includedName := range included {
+			if name == includedName {
+				errs = append(errs, field.Invalid(path.Index(i), name, "is included and excluded at the same time"))
+			}
+		}
+	}
+	return errs
+}
+
+// validateTreeName makes sure the tree name is a single segment of the remote path.
+func validateTreeName(path *field.Path, treeName string) field.ErrorList {
+	if strings.Contains(treeName, "/") || treeName == "." || treeName == ".." {
+		return field.ErrorList{field.Invalid(path, treeName, "must not contain '/' or be a relative path")}
+	}
+	return nil
+}
+
+// validateStorageLocation makes sure the named storage location exists, or that an omitted storage location
+// resolves to exactly one default storage location.
+func validateStorageLocation(ctx context.Context, c client.Reader, path *field.Path, namespace, name string) field.ErrorList {
+	_, err := ResolveStorageLocation(ctx, c, namespace, name)
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsNotFound(err):
+		return field.ErrorList{field.NotFound(path, name)}
+	case errors.Is(err, ErrNoDefaultStorageLocation):
+		return field.ErrorList{field.Required(path, "there is no default storagelocation")}
+	case errors.Is(err, ErrMultipleDefaultStorageLocations):
+		return field.ErrorList{field.Invalid(path, name, err.Error())}
+	}
+	return field.ErrorList{field.InternalError(path, err)}
+}
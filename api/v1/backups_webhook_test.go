@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBackupsValidateImmutableSpec(t *testing.T) {
+	v := &backupsValidator{client: newFakeReader(t, newFileSystemStorageLocation("default", "minio"), newFileSystemStorageLocation("default", "s3"))}
+	old := &Backups{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "daily"},
+		Spec: BackupsSpec{
+			ResourceFilterSpec:      ResourceFilterSpec{IncludedNamespaces: []string{"team-a"}},
+			StorageLocation:         "minio",
+			ReplicaStorageLocations: []string{"s3"},
+		},
+	}
+
+	backup := old.DeepCopy()
+	backup.Spec.IncludedNamespaces = []string{"team-b"}
+	_, err := v.ValidateUpdate(context.Background(), old, backup)
+	expectInvalid(t, err, "only spec.ttl and spec.replicaStorageLocations can be changed")
+
+	backup = old.DeepCopy()
+	backup.Spec.StorageLocation = "s3"
+	_, err = v.ValidateUpdate(context.Background(), old, backup)
+	expectInvalid(t, err, "only spec.ttl and spec.replicaStorageLocations can be changed")
+
+	backup = old.DeepCopy()
+	backup.Spec.ReplicaStorageLocations = nil
+	_, err = v.ValidateUpdate(context.Background(), old, backup)
+	expectInvalid(t, err, "replica storage location s3 can not be removed")
+
+	backup = old.DeepCopy()
+	backup.Spec.ReplicaStorageLocations = append(backup.Spec.ReplicaStorageLocations, "gcs")
+	_, err = v.ValidateUpdate(context.Background(), old, backup)
+	expectInvalid(t, err, "spec.replicaStorageLocations[1]")
+
+	backup = old.DeepCopy()
+	backup.Spec.TTL = &metav1.Duration{Duration: 24 * time.Hour}
+	if _, err = v.ValidateUpdate(context.Background(), old, backup); err != nil {
+		t.Errorf("the ttl update is refused: %v", err)
+	}
+}
+
+func TestRestoresValidateImmutableSpec(t *testing.T) {
+	v := &restoresValidator{client: newFakeReader(t)}
+	old := &Restores{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "daily"},
+		Spec:       RestoresSpec{BackupName: "daily"},
+	}
+
+	restore := old.DeepCopy()
+	restore.Spec.BackupName = "weekly"
+	_, err := v.ValidateUpdate(context.Background(), old, restore)
+	expectInvalid(t, err, "spec: Forbidden: is immutable")
+
+	restore = old.DeepCopy()
+	restore.Labels = map[string]string{"team": "a"}
+	if _, err = v.ValidateUpdate(context.Background(), old, restore); err != nil {
+		t.Errorf("the metadata update is refused: %v", err)
+	}
+}
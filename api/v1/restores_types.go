@@ -25,21 +25,71 @@ import (
 
 // RestoresSpec defines the desired state of Restores
 type RestoresSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// BackupName is the name of the Backups object in the same namespace which is restored.
+	// Either BackupName or TreeName must be set.
+	BackupName string `json:"backupName,omitempty"`
+	// TreeName is the name of the remote backup tree which is restored, it is used when BackupName is empty.
+	TreeName string `json:"treeName,omitempty"`
+	// StorageLocation is the name of the StorageLocations object in the same namespace which the backup is downloaded from.
+	// If it is empty the storage location of the backup is used, or the default storage location for a tree name.
+	StorageLocation string `json:"storageLocation,omitempty"`
 
-	// Foo is an example field of Restores. Edit restores_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	ResourceFilterSpec `json:",inline"`
+}
+
+// RestorePhase is the lifecycle phase of a Restores object
+type RestorePhase string
+
+const (
+	RestorePhaseNew             RestorePhase = "New"
+	RestorePhaseInProgress      RestorePhase = "InProgress"
+	RestorePhaseCompleted       RestorePhase = "Completed"
+	RestorePhasePartiallyFailed RestorePhase = "PartiallyFailed"
+	RestorePhaseFailed          RestorePhase = "Failed"
+)
+
+const (
+	// RestoreConditionCompleted is true once a restore has finished, whatever its result is.
+	RestoreConditionCompleted = "Completed"
+	// RestoreConditionSucceeded tells whether a finished restore has restored every object.
+	RestoreConditionSucceeded = "Succeeded"
+)
+
+// RestoreFailedObject is an object which could not be restored
+type RestoreFailedObject struct {
+	// Name is the path of the object: group/version/resource/namespace/name.
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
 }
 
 // RestoresStatus defines the observed state of Restores
 type RestoresStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	Phase               RestorePhase `json:"phase,omitempty"`
+	StartTimestamp      *metav1.Time `json:"startTimestamp,omitempty"`
+	CompletionTimestamp *metav1.Time `json:"completionTimestamp,omitempty"`
+	// StorageLocation is the resolved storage location which the backup is downloaded from.
+	StorageLocation *StorageLocationReference `json:"storageLocation,omitempty"`
+	// BackupTreeName is the resolved name of the remote backup tree which is restored.
+	BackupTreeName string `json:"backupTreeName,omitempty"`
+	// TreeName is the name of the remote restore tree which records the restored objects.
+	TreeName      string                `json:"treeName,omitempty"`
+	ItemsRestored int                   `json:"itemsRestored,omitempty"`
+	ItemsFailed   int                   `json:"itemsFailed,omitempty"`
+	FailedObjects []RestoreFailedObject `json:"failedObjects,omitempty"`
+	// LogLocation is the remote key of the uploaded restore log, a failed restore uploads its log as well.
+	LogLocation string `json:"logLocation,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Backup Tree",type=string,JSONPath=`.status.backupTreeName`
+//+kubebuilder:printcolumn:name="Storage Location",type=string,JSONPath=`.status.storageLocation.name`
+//+kubebuilder:printcolumn:name="Restored",type=integer,JSONPath=`.status.itemsRestored`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.itemsFailed`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Restores is the Schema for the restores API
 type Restores struct {
@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func (restore *Restores) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(restore).
+		WithValidator(&restoresValidator{client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-boxroom-io-v1-restores,mutating=false,failurePolicy=fail,sideEffects=None,groups=boxroom.io,resources=restores,verbs=create;update,versions=v1,name=vrestores.kb.io,admissionReviewVersions=v1
+
+// restoresValidator needs a client to look up the backup and the storage location a restore refers to.
+type restoresValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &restoresValidator{}
+
+func (v *restoresValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	restore := obj.(*Restores)
+	specPath := field.NewPath("spec")
+
+	errs := restore.Spec.ResourceFilterSpec.validate(specPath)
+	switch {
+	case len(restore.Spec.BackupName) != 0 && len(restore.Spec.TreeName) != 0:
+		errs = append(errs, field.Forbidden(specPath.Child("treeName"), "can not be used together with backupName"))
+	case len(restore.Spec.BackupName) != 0:
+		err := v.client.Get(ctx, types.NamespacedName{Namespace: restore.Namespace, Name: restore.Spec.BackupName}, &Backups{})
+		switch {
+		case apierrors.IsNotFound(err):
+			errs = append(errs, field.NotFound(specPath.Child("backupName"), restore.Spec.BackupName))
+		case err != nil:
+			errs = append(errs, field.InternalError(specPath.Child("backupName"), err))
+		}
+		if len(restore.Spec.StorageLocation) != 0 {
+			errs = append(errs, validateStorageLocation(ctx, v.client, specPath.Child("storageLocation"), restore.Namespace, restore.Spec.StorageLocation)...)
+		}
+	case len(restore.Spec.TreeName) != 0:
+		errs = append(errs, validateTreeName(specPath.Child("treeName"), restore.Spec.TreeName)...)
+		errs = append(errs, validateStorageLocation(ctx, v.client, specPath.Child("storageLocation"), restore.Namespace, restore.Spec.StorageLocation)...)
+	default:
+		errs = append(errs, field.Required(specPath.Child("backupName"), "either backupName or treeName must be set"))
+	}
+
+	if len(errs) != 0 {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Restores").GroupKind(), restore.Name, errs)
+	}
+	return nil, nil
+}
+
+// ValidateUpdate keeps the spec of a restore as it was created.
+func (v *restoresValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldRestore, restore := oldObj.(*Restores), newObj.(*Restores)
+	if !reflect.DeepEqual(oldRestore.Spec, restore.Spec) {
+		errs := field.ErrorList{field.Forbidden(field.NewPath("spec"), "is immutable")}
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Restores").GroupKind(), restore.Name, errs)
+	}
+	return nil, nil
+}
+
+func (v *restoresValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
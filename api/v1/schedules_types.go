@@ -0,0 +1,113 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
+	"github.io/misskaori/boxroom-crd/kubernetes/storage/dir"
+	globleimmobile "github.io/misskaori/boxroom-crd/kubernetes/util/globle-immobile"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScheduleNameLabel is set on every Backups object created by a schedule.
+const ScheduleNameLabel = "boxroom.io/schedule-name"
+
+// SchedulesSpec defines the desired state of Schedules
+type SchedulesSpec struct {
+	// Schedule is a cron expression which defines when backups are taken.
+	Schedule string `json:"schedule"`
+	// Template is the spec of every Backups object created by this schedule.
+	Template BackupsSpec `json:"template"`
+	// Paused stops the schedule from creating new backups.
+	Paused bool `json:"paused,omitempty"`
+	// KeepLast is the number of restorable backups kept for this schedule, older backups are
+	// garbage collected. All backups are kept if it is zero.
+	//+kubebuilder:validation:Minimum=0
+	KeepLast int `json:"keepLast,omitempty"`
+}
+
+// SchedulePhase is the lifecycle phase of a Schedules object
+type SchedulePhase string
+
+const (
+	SchedulePhaseNew              SchedulePhase = "New"
+	SchedulePhaseEnabled          SchedulePhase = "Enabled"
+	SchedulePhasePaused           SchedulePhase = "Paused"
+	SchedulePhaseFailedValidation SchedulePhase = "FailedValidation"
+)
+
+// SchedulesStatus defines the observed state of Schedules
+type SchedulesStatus struct {
+	Phase            SchedulePhase `json:"phase,omitempty"`
+	ValidationErrors []string      `json:"validationErrors,omitempty"`
+	// LastBackup is the due time of the last backup which has been created.
+	LastBackup     *metav1.Time `json:"lastBackup,omitempty"`
+	LastBackupName string       `json:"lastBackupName,omitempty"`
+	NextBackup     *metav1.Time `json:"nextBackup,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Paused",type=boolean,JSONPath=`.spec.paused`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Last Backup",type=date,JSONPath=`.status.lastBackup`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Schedules is the Schema for the schedules API
+type Schedules struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SchedulesSpec   `json:"spec,omitempty"`
+	Status SchedulesStatus `json:"status,omitempty"`
+}
+
+// GetBackup builds the Backups object which this schedule takes for the run due at the given time, the same run
+// always gets the same name. Its tree name follows the timestamped tree names of the storage directory definition.
+func (schedule *Schedules) GetBackup(timestamp time.Time) *Backups {
+	spec := *schedule.Spec.Template.DeepCopy()
+	spec.TreeName = dir.GetTimestampTreeName(immobile.TreeBackupKind, timestamp, schedule.Name)
+
+	return &Backups{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Backups",
+			APIVersion: GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      schedule.Name + "-" + timestamp.Format(globleimmobile.TimestampFormat),
+			Namespace: schedule.Namespace,
+			Labels:    map[string]string{ScheduleNameLabel: schedule.Name},
+		},
+		Spec: spec,
+	}
+}
+
+//+kubebuilder:object:root=true
+
+// SchedulesList contains a list of Schedules
+type SchedulesList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Schedules `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Schedules{}, &SchedulesList{})
+}
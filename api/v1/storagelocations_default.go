@@ -0,0 +1,116 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrNoDefaultStorageLocation is returned when a storage location is omitted but neither the namespace
+// nor the cluster has a default storage location.
+var ErrNoDefaultStorageLocation = errors.New("no default storagelocation")
+
+// ErrMultipleDefaultStorageLocations is returned when a storage location is omitted and the default is ambiguous.
+var ErrMultipleDefaultStorageLocations = errors.New("more than one default storagelocation")
+
+// StorageLocationReference is the storage location which a backup or a restore has been resolved to,
+// a default storage location may live in another namespace than the backup.
+type StorageLocationReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+func (ref StorageLocationReference) String() string {
+	return ref.Namespace + "/" + ref.Name
+}
+
+// GetReference refers to the storage location from a backup or a restore status.
+func (location *StorageLocations) GetReference() *StorageLocationReference {
+	return &StorageLocationReference{Namespace: location.Namespace, Name: location.Name}
+}
+
+// ResolveStorageLocation gets the named storage location of the namespace. When the name is empty it gets
+// the default storage location of the namespace, or the cluster default if the namespace has none.
+func ResolveStorageLocation(ctx context.Context, c client.Reader, namespace, name string) (*StorageLocations, error) {
+	if len(name) != 0 {
+		storageLocation := &StorageLocations{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, storageLocation); err != nil {
+			return nil, err
+		}
+		return storageLocation, nil
+	}
+
+	storageLocation, err := getDefaultStorageLocation(ctx, c, namespace)
+	if storageLocation != nil || err != nil {
+		return storageLocation, err
+	}
+	storageLocation, err = getDefaultStorageLocation(ctx, c, "")
+	if storageLocation != nil || err != nil {
+		return storageLocation, err
+	}
+	return nil, fmt.Errorf("%w for namespace %s", ErrNoDefaultStorageLocation, namespace)
+}
+
+// getDefaultStorageLocation gets the default storage location of the namespace, or the cluster default
+// when the namespace is empty. Storage locations which are being deleted are no longer defaults.
+func getDefaultStorageLocation(ctx context.Context, c client.Reader, namespace string) (*StorageLocations, error) {
+	defaults, err := listDefaultStorageLocations(ctx, c, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(defaults) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &defaults[0], nil
+	}
+
+	names := make([]string, 0, len(defaults))
+	for _, location := range defaults {
+		names = append(names, location.GetReference().String())
+	}
+	scope := "cluster"
+	if len(namespace) != 0 {
+		scope = "namespace " + namespace
+	}
+	return nil, fmt.Errorf("%w in %s: %s", ErrMultipleDefaultStorageLocations, scope, strings.Join(names, ", "))
+}
+
+func listDefaultStorageLocations(ctx context.Context, c client.Reader, namespace string) ([]StorageLocations, error) {
+	locationList := &StorageLocationsList{}
+	if err := c.List(ctx, locationList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	var defaults []StorageLocations
+	for _, location := range locationList.Items {
+		if location.DeletionTimestamp != nil {
+			continue
+		}
+		if (len(namespace) != 0 && location.Spec.Default) || (len(namespace) == 0 && location.Spec.ClusterDefault) {
+			defaults = append(defaults, location)
+		}
+	}
+	return defaults, nil
+}
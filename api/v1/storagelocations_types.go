@@ -17,9 +17,12 @@ limitations under the License.
 package v1
 
 import (
+	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"net"
 	"strconv"
 )
 
@@ -34,43 +37,176 @@ type StorageLocationsSpec struct {
 	// Foo is an example field of StorageLocations. Edit storagelocations_types.go to remove/update
 	ContainerSpec *StoragePluginContainerSpec `json:"containerSpec,omitempty"`
 	ConfigSpec    *StoragePluginConfigSpec    `json:"configSpec,omitempty"`
+	// DeletionPolicy decides what happens to the backups of the storage location when it is deleted, defaults to Block.
+	// +kubebuilder:validation:Enum=Block;Orphan
+	DeletionPolicy StorageLocationDeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Default makes the storage location the default of its namespace, which is used by the backups and restores
+	// of the namespace that do not name a storage location. At most one storage location per namespace is the default.
+	Default bool `json:"default,omitempty"`
+	// ClusterDefault makes the storage location the default of every namespace which has no default of its own.
+	// At most one storage location in the cluster is the cluster default.
+	ClusterDefault bool `json:"clusterDefault,omitempty"`
 }
 
+// StorageLocationDeletionPolicy decides what happens to the backups of a deleted storage location.
+type StorageLocationDeletionPolicy string
+
+const (
+	// StorageLocationDeletionPolicyBlock keeps the storage location until no backup refers to it any more.
+	StorageLocationDeletionPolicyBlock StorageLocationDeletionPolicy = "Block"
+	// StorageLocationDeletionPolicyOrphan marks the backups as orphaned and deletes the storage location,
+	// the backup trees are kept in the storage.
+	StorageLocationDeletionPolicyOrphan StorageLocationDeletionPolicy = "Orphan"
+)
+
+// StorageLocationFinalizer keeps a deleted storage location until its plugin workload is torn down
+// and its backups are handled according to the deletion policy.
+const StorageLocationFinalizer = "boxroom.io/storagelocation-cleanup"
+
+const (
+	// DefaultStoragePluginPort is the port the storage plugin listens on when the container spec has no port.
+	DefaultStoragePluginPort = 8082
+	// StoragePluginPortName names the port of the storage plugin container, the service targets it by name.
+	StoragePluginPortName = "storage-plugin"
+)
+
 type StoragePluginContainerSpec struct {
-	Replicas int         `json:"replicas,omitempty"`
+	Replicas int `json:"replicas,omitempty"`
+	// Image is the storage plugin image, it is required by the rpc-plugin client. There is no default because
+	// the image has to be built for the architecture of the nodes the plugin runs on.
 	Image    string      `json:"image,omitempty"`
 	Port     int32       `json:"port,omitempty"`
 	Protocol v1.Protocol `json:"protocol,omitempty"`
+	// ServicePort is the port the service exposes the storage plugin on, defaults to Port.
+	ServicePort int32 `json:"servicePort,omitempty"`
+
+	ImagePullPolicy    v1.PullPolicy             `json:"imagePullPolicy,omitempty"`
+	ImagePullSecrets   []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	Resources          v1.ResourceRequirements   `json:"resources,omitempty"`
+	NodeSelector       map[string]string         `json:"nodeSelector,omitempty"`
+	Tolerations        []v1.Toleration           `json:"tolerations,omitempty"`
+	Affinity           *v1.Affinity              `json:"affinity,omitempty"`
+	ServiceAccountName string                    `json:"serviceAccountName,omitempty"`
+	// Env is appended to the environment the storage plugin is configured with.
+	Env          []v1.EnvVar      `json:"env,omitempty"`
+	Volumes      []v1.Volume      `json:"volumes,omitempty"`
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+}
+
+func (spec *StoragePluginContainerSpec) GetPort() int32 {
+	if spec.Port == 0 {
+		return DefaultStoragePluginPort
+	}
+	return spec.Port
+}
+
+func (spec *StoragePluginContainerSpec) GetProtocol() v1.Protocol {
+	if len(spec.Protocol) == 0 {
+		return v1.ProtocolTCP
+	}
+	return spec.Protocol
+}
+
+func (spec *StoragePluginContainerSpec) GetServicePort() int32 {
+	if spec.ServicePort == 0 {
+		return spec.GetPort()
+	}
+	return spec.ServicePort
 }
 
 type StoragePluginConfigSpec struct {
+	// Client is the store client which the controller reaches the storage with, one of rpc-plugin, filesystem
+	// or s3. Only rpc-plugin runs the storage plugin pods, it is the default.
+	Client string `json:"client,omitempty"`
+	// StorageKind is handed to the storage plugin as STORAGE_KIND, it selects the backend of the plugin.
 	StorageKind   string                     `json:"storageKind,omitempty"`
 	StorageConfig *StorageLocationSpecConfig `json:"config,omitempty"`
 }
 
 type StorageLocationSpecConfig struct {
-	StorageUrl       string `json:"storageUrl,omitempty"`
-	Region           string `json:"region,omitempty"`
-	Bucket           string `json:"bucket,omitempty"`
+	StorageUrl string `json:"storageUrl,omitempty"`
+	Region     string `json:"region,omitempty"`
+	Bucket     string `json:"bucket,omitempty"`
+	// AccessKey and SecretKey are readable by everyone who can read the storage location,
+	// they are only used when CredentialsSecretRef is not set.
 	AccessKey        string `json:"accessKey,omitempty"`
 	SecretKey        string `json:"secretKey,omitempty"`
 	DisableSSL       bool   `json:"disableSSL,omitempty"`
 	S3ForcePathStyle bool   `json:"s3ForcePathStyle,omitempty"`
+	// CredentialsSecretRef points to a secret in the namespace of the storage location which holds the keys.
+	CredentialsSecretRef *CredentialsSecretRef `json:"credentialsSecretRef,omitempty"`
+}
+
+const (
+	DefaultAccessKeySecretKey = "accessKey"
+	DefaultSecretKeySecretKey = "secretKey"
+)
+
+type CredentialsSecretRef struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// AccessKey is the key of the access key in the secret, defaults to accessKey.
+	AccessKey string `json:"accessKey,omitempty"`
+	// SecretKey is the key of the secret key in the secret, defaults to secretKey.
+	SecretKey string `json:"secretKey,omitempty"`
 }
 
+func (ref *CredentialsSecretRef) GetAccessKey() string {
+	if len(ref.AccessKey) == 0 {
+		return DefaultAccessKeySecretKey
+	}
+	return ref.AccessKey
+}
+
+func (ref *CredentialsSecretRef) GetSecretKey() string {
+	if len(ref.SecretKey) == 0 {
+		return DefaultSecretKeySecretKey
+	}
+	return ref.SecretKey
+}
+
+const (
+	// StorageLocationConditionCredentialsReady tells whether the referenced credentials secret exists and holds both keys.
+	StorageLocationConditionCredentialsReady = "CredentialsReady"
+	// StorageLocationConditionAvailable tells whether the last validation of the storage has passed,
+	// backups and restores are refused while it is false.
+	StorageLocationConditionAvailable = "Available"
+	// StorageLocationConditionDegraded is true when the validation has failed or fewer storage plugin pods are ready than desired.
+	StorageLocationConditionDegraded = "Degraded"
+	// StorageLocationConditionDeletionBlocked is true while the deletion waits for the backups which refer to the storage location.
+	StorageLocationConditionDeletionBlocked = "DeletionBlocked"
+)
+
 // StorageLocationsStatus defines the observed state of StorageLocations
 type StorageLocationsStatus struct {
-	Replicas  int      `json:"replicas,omitempty"`
-	Pods      []string `json:"pods,omitempty"`
-	Service   string   `json:"service,omitempty"`
-	ServiceIp string   `json:"serviceIp,omitempty"`
-	ServicePort string `json:"servicePort,omitempty"`
+	// ObservedGeneration is the generation of the spec the status has been reconciled from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	Replicas    int      `json:"replicas,omitempty"`
+	Pods        []string `json:"pods,omitempty"`
+	Service     string   `json:"service,omitempty"`
+	ServiceIp   string   `json:"serviceIp,omitempty"`
+	ServicePort string   `json:"servicePort,omitempty"`
+	// CredentialsSecret is the name of the validated credentials secret.
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+	// LastValidationTime is when the storage was last checked through the store client.
+	LastValidationTime *metav1.Time `json:"lastValidationTime,omitempty"`
+	// LastValidationError is the error of the last validation, empty if it has passed.
+	LastValidationError string `json:"lastValidationError,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 }
 
 //+kubebuilder:object:root=true
-//+kubebuilder:subresource:statussd
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Client",type=string,JSONPath=`.spec.configSpec.client`
+//+kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.configSpec.storageKind`
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Default",type=boolean,JSONPath=`.spec.default`
+//+kubebuilder:printcolumn:name="Last Validated",type=date,JSONPath=`.status.lastValidationTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // StorageLocations is the Schema for the storagelocations API
 type StorageLocations struct {
@@ -81,51 +217,63 @@ type StorageLocations struct {
 	Status StorageLocationsStatus `json:"status,omitempty"`
 }
 
-func (location *StorageLocations) GetPod() *v1.Pod {
+func (location *StorageLocations) GetWorkloadLabels() map[string]string {
+	return map[string]string{"workload-kind": "storagelocations", "workload-name": location.Name}
+}
+
+func (location *StorageLocations) GetDeploymentName() string {
+	return location.Name + "-plugin"
+}
+
+// NeedsStoragePlugin tells whether the storage is reached through the storage plugin pods,
+// the other store clients reach the storage from the controller itself.
+func (location *StorageLocations) NeedsStoragePlugin() bool {
+	return location.GetClientKind() == storeclient.RpcPluginClientKind
+}
+
+// GetDeployment builds the deployment of the storage plugin, every change of the container spec
+// or the storage config rolls out new plugin pods.
+func (location *StorageLocations) GetDeployment() *appsv1.Deployment {
 	typeMeta := metav1.TypeMeta{
-		Kind:       "Pod",
-		APIVersion: "v1",
+		Kind:       "Deployment",
+		APIVersion: "apps/v1",
 	}
 
 	objectMeta := metav1.ObjectMeta{
-		GenerateName: location.Name + "-",
-		Namespace:    location.Namespace,
-		Labels:       map[string]string{"workload-kind": "storagelocations", "workload-name": location.Name},
-		OwnerReferences: []metav1.OwnerReference{
-			{
-				APIVersion: location.APIVersion,
-				Kind:       location.Kind,
-				Name:       location.Name,
-				UID:        location.UID,
-			},
-		},
+		Name:      location.GetDeploymentName(),
+		Namespace: location.Namespace,
+		Labels:    location.GetWorkloadLabels(),
+	}
+
+	containerSpec := location.Spec.ContainerSpec
+	replicas := int32(containerSpec.Replicas)
+	if !location.NeedsStoragePlugin() {
+		replicas = 0
 	}
 
-	spec := v1.PodSpec{
+	podSpec := v1.PodSpec{
 		Containers: []v1.Container{
 			{
-				Name:  "storage-plugin",
-				Image: "liuweizhe/boxroom-s3-plugin:1.0-arm64",
+				Name:            "storage-plugin",
+				Image:           containerSpec.Image,
+				ImagePullPolicy: containerSpec.ImagePullPolicy,
+				Resources:       containerSpec.Resources,
+				VolumeMounts:    containerSpec.VolumeMounts,
 				Ports: []v1.ContainerPort{
 					{
-						ContainerPort: location.Spec.ContainerSpec.Port,
-						Protocol:      location.Spec.ContainerSpec.Protocol,
+						Name:          StoragePluginPortName,
+						ContainerPort: containerSpec.GetPort(),
+						Protocol:      containerSpec.GetProtocol(),
 					},
 				},
 
-				Env: []v1.EnvVar{
+				Env: append([]v1.EnvVar{
 					{
 						Name:  "STORAGE_KIND",
 						Value: location.Spec.ConfigSpec.StorageKind,
 					},
-					{
-						Name:  "ACCESS_KEY",
-						Value: location.Spec.ConfigSpec.StorageConfig.AccessKey,
-					},
-					{
-						Name:  "SECRET_KEY",
-						Value: location.Spec.ConfigSpec.StorageConfig.SecretKey,
-					},
+					location.getCredentialsEnv("ACCESS_KEY", location.Spec.ConfigSpec.StorageConfig.AccessKey, (*CredentialsSecretRef).GetAccessKey),
+					location.getCredentialsEnv("SECRET_KEY", location.Spec.ConfigSpec.StorageConfig.SecretKey, (*CredentialsSecretRef).GetSecretKey),
 					{
 						Name:  "ENDPOINT",
 						Value: location.Spec.ConfigSpec.StorageConfig.StorageUrl,
@@ -146,19 +294,70 @@ func (location *StorageLocations) GetPod() *v1.Pod {
 						Name:  "S3_FORCE_PATH_STYLE",
 						Value: strconv.FormatBool(location.Spec.ConfigSpec.StorageConfig.S3ForcePathStyle),
 					},
-				},
+				}, containerSpec.Env...),
+			},
+		},
+		ImagePullSecrets:   containerSpec.ImagePullSecrets,
+		NodeSelector:       containerSpec.NodeSelector,
+		Tolerations:        containerSpec.Tolerations,
+		Affinity:           containerSpec.Affinity,
+		ServiceAccountName: containerSpec.ServiceAccountName,
+		Volumes:            containerSpec.Volumes,
+		RestartPolicy:      v1.RestartPolicyAlways,
+	}
+
+	spec := appsv1.DeploymentSpec{
+		Replicas: &replicas,
+		Selector: &metav1.LabelSelector{
+			MatchLabels: location.GetWorkloadLabels(),
+		},
+		Template: v1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: location.GetWorkloadLabels(),
 			},
+			Spec: podSpec,
 		},
-		RestartPolicy: v1.RestartPolicyAlways,
 	}
 
-	newPod := &v1.Pod{
+	newDeployment := &appsv1.Deployment{
 		TypeMeta:   typeMeta,
 		ObjectMeta: objectMeta,
 		Spec:       spec,
 	}
 
-	return newPod
+	return newDeployment
+}
+
+// getCredentialsEnv reads the credential from the credentials secret when it is referenced, so it never shows up in the pod spec.
+func (location *StorageLocations) getCredentialsEnv(name, value string, getKey func(ref *CredentialsSecretRef) string) v1.EnvVar {
+	ref := location.Spec.ConfigSpec.StorageConfig.CredentialsSecretRef
+	if ref == nil {
+		return v1.EnvVar{
+			Name:  name,
+			Value: value,
+		}
+	}
+
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: ref.Name},
+				Key:                  getKey(ref),
+			},
+		},
+	}
+}
+
+func (location *StorageLocations) GetCredentialsSecretRef() *CredentialsSecretRef {
+	if location.Spec.ConfigSpec == nil || location.Spec.ConfigSpec.StorageConfig == nil {
+		return nil
+	}
+	return location.Spec.ConfigSpec.StorageConfig.CredentialsSecretRef
+}
+
+func (location *StorageLocations) GetServiceName() string {
+	return location.Name + "-service"
 }
 
 func (location *StorageLocations) GetService() *v1.Service {
@@ -168,29 +367,19 @@ func (location *StorageLocations) GetService() *v1.Service {
 	}
 
 	objectMeta := metav1.ObjectMeta{
-		Name:      location.Name + "-service",
+		Name:      location.GetServiceName(),
 		Namespace: location.Namespace,
-		Labels:    map[string]string{"workload-kind": "storagelocations", "workload-name": location.Name},
-		OwnerReferences: []metav1.OwnerReference{
-			{
-				APIVersion: location.APIVersion,
-				Kind:       location.Kind,
-				Name:       location.Name,
-				UID:        location.UID,
-			},
-		},
+		Labels:    location.GetWorkloadLabels(),
 	}
 
 	spec := v1.ServiceSpec{
-		Selector: map[string]string{"workload-kind": "storagelocations", "workload-name": location.Name},
+		Selector: location.GetWorkloadLabels(),
 		Ports: []v1.ServicePort{
 			{
-				Protocol: "TCP",
-				Port:     8081,
-				TargetPort: intstr.IntOrString{
-					Type:   intstr.Int,
-					IntVal: 8082,
-				},
+				Name:       StoragePluginPortName,
+				Protocol:   location.Spec.ContainerSpec.GetProtocol(),
+				Port:       location.Spec.ContainerSpec.GetServicePort(),
+				TargetPort: intstr.FromString(StoragePluginPortName),
 			},
 		},
 	}
@@ -204,6 +393,41 @@ func (location *StorageLocations) GetService() *v1.Service {
 	return newService
 }
 
+func (location *StorageLocations) GetStoragePluginUrl() string {
+	if len(location.Status.ServiceIp) == 0 {
+		return ""
+	}
+	return net.JoinHostPort(location.Status.ServiceIp, location.Status.ServicePort)
+}
+
+func (location *StorageLocations) GetClientKind() string {
+	if location.Spec.ConfigSpec == nil || len(location.Spec.ConfigSpec.Client) == 0 {
+		return storeclient.DefaultClientKind
+	}
+	return location.Spec.ConfigSpec.Client
+}
+
+// GetStoreClientSettings collects the settings which the store client of the storage location is built from,
+// the keys of a credentials secret have to be filled in by the caller.
+func (location *StorageLocations) GetStoreClientSettings() *storeclient.StoreClientSettings {
+	settings := &storeclient.StoreClientSettings{
+		StoragePluginUrl: location.GetStoragePluginUrl(),
+	}
+	if location.Spec.ConfigSpec == nil || location.Spec.ConfigSpec.StorageConfig == nil {
+		return settings
+	}
+
+	config := location.Spec.ConfigSpec.StorageConfig
+	settings.StorageUrl = config.StorageUrl
+	settings.Region = config.Region
+	settings.Bucket = config.Bucket
+	settings.AccessKey = config.AccessKey
+	settings.SecretKey = config.SecretKey
+	settings.DisableSSL = config.DisableSSL
+	settings.S3ForcePathStyle = config.S3ForcePathStyle
+	return settings
+}
+
 //+kubebuilder:object:root=true
 
 // StorageLocationsList contains a list of StorageLocations
@@ -0,0 +1,251 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// bucketNamePattern follows the naming rules of s3 buckets, which are valid directory names as well.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+func (location *StorageLocations) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(location).
+		WithValidator(&storageLocationsValidator{client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-boxroom-io-v1-storagelocations,mutating=true,failurePolicy=fail,sideEffects=None,groups=boxroom.io,resources=storagelocations,verbs=create;update,versions=v1,name=mstoragelocations.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &StorageLocations{}
+
+// Default fills in the container spec and the config spec, the reconciler calls it as well
+// so a storage location which has not passed the webhook is still complete.
+func (location *StorageLocations) Default() {
+	if len(location.Spec.DeletionPolicy) == 0 {
+		location.Spec.DeletionPolicy = StorageLocationDeletionPolicyBlock
+	}
+
+	if location.Spec.ConfigSpec == nil {
+		location.Spec.ConfigSpec = &StoragePluginConfigSpec{}
+	}
+	if len(location.Spec.ConfigSpec.Client) == 0 {
+		location.Spec.ConfigSpec.Client = storeclient.DefaultClientKind
+	}
+	if location.Spec.ConfigSpec.StorageConfig == nil {
+		location.Spec.ConfigSpec.StorageConfig = &StorageLocationSpecConfig{}
+	}
+
+	if location.Spec.ContainerSpec == nil {
+		location.Spec.ContainerSpec = &StoragePluginContainerSpec{}
+	}
+	containerSpec := location.Spec.ContainerSpec
+	if containerSpec.Replicas == 0 && location.NeedsStoragePlugin() {
+		containerSpec.Replicas = 1
+	}
+	containerSpec.Port = containerSpec.GetPort()
+	containerSpec.Protocol = containerSpec.GetProtocol()
+}
+
+//+kubebuilder:webhook:path=/validate-boxroom-io-v1-storagelocations,mutating=false,failurePolicy=fail,sideEffects=None,groups=boxroom.io,resources=storagelocations,verbs=create;update,versions=v1,name=vstoragelocations.kb.io,admissionReviewVersions=v1
+
+// storageLocationsValidator needs a client to check that there is at most one default storage location.
+type storageLocationsValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &storageLocationsValidator{}
+
+func (v *storageLocationsValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	location := obj.(*StorageLocations)
+	return location.validate(nil, v.validateDefault(ctx, location))
+}
+
+// ValidateUpdate only checks updates which change the spec of a storage location which is not being deleted,
+// the finalizer and the other metadata are always let through so a storage location that does not validate
+// anymore can still be set up and torn down by the reconciler.
+func (v *storageLocationsValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldLocation, location := oldObj.(*StorageLocations), newObj.(*StorageLocations)
+	if location.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	// the new object has been through the defaulting webhook, the old one may be stored without the defaults
+	defaulted := oldLocation.DeepCopy()
+	defaulted.Default()
+	if reflect.DeepEqual(defaulted.Spec, location.Spec) {
+		return nil, nil
+	}
+	return location.validate(oldLocation, v.validateDefault(ctx, location))
+}
+
+func (v *storageLocationsValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateDefault refuses a second default storage location in the namespace, or a second cluster default.
+func (v *storageLocationsValidator) validateDefault(ctx context.Context, location *StorageLocations) field.ErrorList {
+	var errs field.ErrorList
+	if location.Spec.Default {
+		errs = append(errs, v.validateUniqueDefault(ctx, location, field.NewPath("spec", "default"), location.Namespace)...)
+	}
+	if location.Spec.ClusterDefault {
+		errs = append(errs, v.validateUniqueDefault(ctx, location, field.NewPath("spec", "clusterDefault"), "")...)
+	}
+	return errs
+}
+
+func (v *storageLocationsValidator) validateUniqueDefault(ctx context.Context, location *StorageLocations, path *field.Path, namespace string) field.ErrorList {
+	defaults, err := listDefaultStorageLocations(ctx, v.client, namespace)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+
+	for _, other := range defaults {
+		if other.Namespace != location.Namespace || other.Name != location.Name {
+			return field.ErrorList{field.Forbidden(path, fmt.Sprintf("storagelocation %s is already the default", other.GetReference()))}
+		}
+	}
+	return nil
+}
+
+// validate checks a defaulted storage location, the client and the bucket can not be changed
+// because the existing backups would be left behind in the old storage.
+func (location *StorageLocations) validate(old *StorageLocations, errs field.ErrorList) (admission.Warnings, error) {
+	var warnings admission.Warnings
+
+	containerPath := field.NewPath("spec", "containerSpec")
+	configPath := field.NewPath("spec", "configSpec")
+	if location.Spec.ContainerSpec == nil {
+		errs = append(errs, field.Required(containerPath, ""))
+	}
+	if location.Spec.ConfigSpec == nil || location.Spec.ConfigSpec.StorageConfig == nil {
+		errs = append(errs, field.Required(configPath.Child("config"), ""))
+	}
+	if len(errs) != 0 {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("StorageLocations").GroupKind(), location.Name, errs)
+	}
+
+	containerSpec := location.Spec.ContainerSpec
+	if len(containerSpec.Image) == 0 && location.NeedsStoragePlugin() {
+		errs = append(errs, field.Required(containerPath.Child("image"), "the storage plugin image is required by the rpc-plugin client"))
+	}
+	if containerSpec.Replicas < 0 {
+		errs = append(errs, field.Invalid(containerPath.Child("replicas"), containerSpec.Replicas, "must not be negative"))
+	}
+	if containerSpec.Port < 1 || containerSpec.Port > 65535 {
+		errs = append(errs, field.Invalid(containerPath.Child("port"), containerSpec.Port, "must be between 1 and 65535"))
+	}
+	if containerSpec.ServicePort < 0 || containerSpec.ServicePort > 65535 {
+		errs = append(errs, field.Invalid(containerPath.Child("servicePort"), containerSpec.ServicePort, "must be between 1 and 65535, or 0 for the container port"))
+	}
+	if containerSpec.Protocol != v1.ProtocolTCP {
+		errs = append(errs, field.NotSupported(containerPath.Child("protocol"), containerSpec.Protocol, []string{string(v1.ProtocolTCP)}))
+	}
+
+	// the client kinds are registered by the store client implementations which are linked into the manager,
+	// the storage kind belongs to the storage plugin and is not checked here
+	clientKind := location.Spec.ConfigSpec.Client
+	clientKinds := storeclient.GetStoreClientKinds()
+	knownKind := false
+	for _, kind := range clientKinds {
+		knownKind = knownKind || kind == clientKind
+	}
+	if !knownKind {
+		errs = append(errs, field.NotSupported(configPath.Child("client"), clientKind, clientKinds))
+	}
+
+	config := location.Spec.ConfigSpec.StorageConfig
+	storageConfigPath := configPath.Child("config")
+	if len(config.Bucket) == 0 {
+		errs = append(errs, field.Required(storageConfigPath.Child("bucket"), ""))
+	} else if !bucketNamePattern.MatchString(config.Bucket) || strings.Contains(config.Bucket, "..") {
+		errs = append(errs, field.Invalid(storageConfigPath.Child("bucket"), config.Bucket,
+			"must be 3 to 63 lowercase letters, digits, dots or hyphens, beginning and ending with a letter or digit"))
+	}
+
+	switch clientKind {
+	case storeclient.FileSystemClientKind:
+		if !filepath.IsAbs(config.StorageUrl) {
+			errs = append(errs, field.Invalid(storageConfigPath.Child("storageUrl"), config.StorageUrl, "must be the absolute path of the root dir"))
+		}
+	default:
+		if len(config.StorageUrl) != 0 {
+			if err := validateStorageUrl(config.StorageUrl); err != "" {
+				errs = append(errs, field.Invalid(storageConfigPath.Child("storageUrl"), config.StorageUrl, err))
+			}
+		}
+	}
+
+	if config.CredentialsSecretRef != nil {
+		if len(config.AccessKey) != 0 || len(config.SecretKey) != 0 {
+			errs = append(errs, field.Forbidden(storageConfigPath.Child("credentialsSecretRef"), "can not be used together with accessKey and secretKey"))
+		}
+	} else if len(config.AccessKey) != 0 || len(config.SecretKey) != 0 {
+		warnings = append(warnings, "accessKey and secretKey are readable by everyone who can read the storagelocation, use credentialsSecretRef instead")
+	}
+
+	if old != nil && old.Spec.ConfigSpec != nil {
+		if old.Spec.ConfigSpec.Client != clientKind {
+			errs = append(errs, field.Forbidden(configPath.Child("client"), "is immutable"))
+		}
+		if old.Spec.ConfigSpec.StorageConfig != nil && old.Spec.ConfigSpec.StorageConfig.Bucket != config.Bucket {
+			errs = append(errs, field.Forbidden(storageConfigPath.Child("bucket"), "is immutable"))
+		}
+	}
+
+	if len(errs) != 0 {
+		return warnings, apierrors.NewInvalid(GroupVersion.WithKind("StorageLocations").GroupKind(), location.Name, errs)
+	}
+	return warnings, nil
+}
+
+// validateStorageUrl accepts a host with an optional port, or an http or https url.
+func validateStorageUrl(storageUrl string) string {
+	if !strings.Contains(storageUrl, "://") {
+		storageUrl = "https://" + storageUrl
+	}
+
+	parsedUrl, err := url.Parse(storageUrl)
+	if err != nil {
+		return err.Error()
+	}
+	if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+		return "the scheme must be http or https"
+	}
+	if len(parsedUrl.Hostname()) == 0 {
+		return "must contain a host"
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
+	_ "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client/filesystem"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newFakeReader builds the client which the validators look up the other objects with.
+func newFakeReader(t *testing.T, objects ...client.Object) client.Reader {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+// newFileSystemStorageLocation builds a defaulted storage location which passes the validation.
+func newFileSystemStorageLocation(namespace, name string) *StorageLocations {
+	location := &StorageLocations{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: StorageLocationsSpec{
+			ConfigSpec: &StoragePluginConfigSpec{
+				Client:        storeclient.FileSystemClientKind,
+				StorageConfig: &StorageLocationSpecConfig{Bucket: "backups", StorageUrl: "/data"},
+			},
+		},
+	}
+	location.Default()
+	return location
+}
+
+func expectInvalid(t *testing.T, err error, field string) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), field) {
+		t.Errorf("error is %v, want %s to be refused", err, field)
+	}
+}
+
+func TestStorageLocationsValidateUniqueDefault(t *testing.T) {
+	namespaceDefault := newFileSystemStorageLocation("team-a", "default")
+	namespaceDefault.Spec.Default = true
+	clusterDefault := newFileSystemStorageLocation("kube-system", "cluster")
+	clusterDefault.Spec.ClusterDefault = true
+	deletedDefault := newFileSystemStorageLocation("team-b", "deleted")
+	deletedDefault.Spec.Default = true
+	deletedDefault.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deletedDefault.Finalizers = []string{StorageLocationFinalizer}
+	v := &storageLocationsValidator{client: newFakeReader(t, namespaceDefault, clusterDefault, deletedDefault)}
+
+	location := newFileSystemStorageLocation("team-a", "second")
+	location.Spec.Default = true
+	_, err := v.ValidateCreate(context.Background(), location)
+	expectInvalid(t, err, "spec.default")
+
+	location = newFileSystemStorageLocation("team-a", "second")
+	location.Spec.ClusterDefault = true
+	_, err = v.ValidateCreate(context.Background(), location)
+	expectInvalid(t, err, "spec.clusterDefault")
+
+	// a default of another namespace, or one which is being deleted, does not count
+	location = newFileSystemStorageLocation("team-b", "second")
+	location.Spec.Default = true
+	if _, err = v.ValidateCreate(context.Background(), location); err != nil {
+		t.Errorf("the first default of namespace team-b is refused: %v", err)
+	}
+
+	// the default itself may be updated
+	updated := namespaceDefault.DeepCopy()
+	updated.Spec.ContainerSpec.Replicas = 2
+	if _, err = v.ValidateUpdate(context.Background(), namespaceDefault, updated); err != nil {
+		t.Errorf("the update of the default is refused: %v", err)
+	}
+}
+
+func TestStorageLocationsValidateImmutableStorage(t *testing.T) {
+	v := &storageLocationsValidator{client: newFakeReader(t)}
+	old := newFileSystemStorageLocation("default", "minio")
+
+	location := old.DeepCopy()
+	location.Spec.ConfigSpec.StorageConfig.Bucket = "other-backups"
+	_, err := v.ValidateUpdate(context.Background(), old, location)
+	expectInvalid(t, err, "spec.configSpec.config.bucket")
+
+	location = old.DeepCopy()
+	location.Spec.ConfigSpec.Client = storeclient.RpcPluginClientKind
+	_, err = v.ValidateUpdate(context.Background(), old, location)
+	expectInvalid(t, err, "spec.configSpec.client")
+
+	// a storage location which does not validate any more can still get its finalizer and be deleted
+	invalid := old.DeepCopy()
+	invalid.Spec.ConfigSpec.StorageConfig.StorageUrl = "data"
+	location = invalid.DeepCopy()
+	location.Finalizers = []string{StorageLocationFinalizer}
+	if _, err = v.ValidateUpdate(context.Background(), invalid, location); err != nil {
+		t.Errorf("the finalizer of an invalid storage location is refused: %v", err)
+	}
+	location.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	location.Spec.ConfigSpec.StorageConfig.Bucket = "other-backups"
+	if _, err = v.ValidateUpdate(context.Background(), invalid, location); err != nil {
+		t.Errorf("the update of a deleted storage location is refused: %v", err)
+	}
+}
@@ -22,16 +22,38 @@ limitations under the License.
 package v1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStorageLocationStatus) DeepCopyInto(out *BackupStorageLocationStatus) {
+	*out = *in
+	out.StorageLocationReference = in.StorageLocationReference
+	if in.CompletionTimestamp != nil {
+		in, out := &in.CompletionTimestamp, &out.CompletionTimestamp
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorageLocationStatus.
+func (in *BackupStorageLocationStatus) DeepCopy() *BackupStorageLocationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStorageLocationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Backups) DeepCopyInto(out *Backups) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Backups.
@@ -87,6 +109,22 @@ func (in *BackupsList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BackupsSpec) DeepCopyInto(out *BackupsSpec) {
 	*out = *in
+	if in.ReplicaStorageLocations != nil {
+		in, out := &in.ReplicaStorageLocations, &out.ReplicaStorageLocations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.DefaultFilters != nil {
+		in, out := &in.DefaultFilters, &out.DefaultFilters
+		*out = new(DefaultFiltersSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ResourceFilterSpec.DeepCopyInto(&out.ResourceFilterSpec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupsSpec.
@@ -102,6 +140,37 @@ func (in *BackupsSpec) DeepCopy() *BackupsSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BackupsStatus) DeepCopyInto(out *BackupsStatus) {
 	*out = *in
+	if in.StartTimestamp != nil {
+		in, out := &in.StartTimestamp, &out.StartTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTimestamp != nil {
+		in, out := &in.CompletionTimestamp, &out.CompletionTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.Expiration != nil {
+		in, out := &in.Expiration, &out.Expiration
+		*out = (*in).DeepCopy()
+	}
+	if in.StorageLocation != nil {
+		in, out := &in.StorageLocation, &out.StorageLocation
+		*out = new(StorageLocationReference)
+		**out = **in
+	}
+	if in.StorageLocations != nil {
+		in, out := &in.StorageLocations, &out.StorageLocations
+		*out = make([]BackupStorageLocationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupsStatus.
@@ -114,13 +183,160 @@ func (in *BackupsStatus) DeepCopy() *BackupsStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsSecretRef) DeepCopyInto(out *CredentialsSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsSecretRef.
+func (in *CredentialsSecretRef) DeepCopy() *CredentialsSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefaultFiltersSpec) DeepCopyInto(out *DefaultFiltersSpec) {
+	*out = *in
+	if in.ExcludedNamespaces != nil {
+		in, out := &in.ExcludedNamespaces, &out.ExcludedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedResources != nil {
+		in, out := &in.ExcludedResources, &out.ExcludedResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludedClusterResources != nil {
+		in, out := &in.IncludedClusterResources, &out.IncludedClusterResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedObjects != nil {
+		in, out := &in.ExcludedObjects, &out.ExcludedObjects
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultFiltersSpec.
+func (in *DefaultFiltersSpec) DeepCopy() *DefaultFiltersSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultFiltersSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectFilter) DeepCopyInto(out *ObjectFilter) {
+	*out = *in
+	if in.IncludedNames != nil {
+		in, out := &in.IncludedNames, &out.IncludedNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedNames != nil {
+		in, out := &in.ExcludedNames, &out.ExcludedNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectFilter.
+func (in *ObjectFilter) DeepCopy() *ObjectFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceFilterSpec) DeepCopyInto(out *ResourceFilterSpec) {
+	*out = *in
+	if in.IncludedNamespaces != nil {
+		in, out := &in.IncludedNamespaces, &out.IncludedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedNamespaces != nil {
+		in, out := &in.ExcludedNamespaces, &out.ExcludedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludedResources != nil {
+		in, out := &in.IncludedResources, &out.IncludedResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedResources != nil {
+		in, out := &in.ExcludedResources, &out.ExcludedResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObjectFilters != nil {
+		in, out := &in.ObjectFilters, &out.ObjectFilters
+		*out = make([]ObjectFilter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceFilterSpec.
+func (in *ResourceFilterSpec) DeepCopy() *ResourceFilterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceFilterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreFailedObject) DeepCopyInto(out *RestoreFailedObject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreFailedObject.
+func (in *RestoreFailedObject) DeepCopy() *RestoreFailedObject {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreFailedObject)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Restores) DeepCopyInto(out *Restores) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Restores.
@@ -176,6 +392,7 @@ func (in *RestoresList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestoresSpec) DeepCopyInto(out *RestoresSpec) {
 	*out = *in
+	in.ResourceFilterSpec.DeepCopyInto(&out.ResourceFilterSpec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoresSpec.
@@ -191,6 +408,31 @@ func (in *RestoresSpec) DeepCopy() *RestoresSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestoresStatus) DeepCopyInto(out *RestoresStatus) {
 	*out = *in
+	if in.StartTimestamp != nil {
+		in, out := &in.StartTimestamp, &out.StartTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTimestamp != nil {
+		in, out := &in.CompletionTimestamp, &out.CompletionTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.StorageLocation != nil {
+		in, out := &in.StorageLocation, &out.StorageLocation
+		*out = new(StorageLocationReference)
+		**out = **in
+	}
+	if in.FailedObjects != nil {
+		in, out := &in.FailedObjects, &out.FailedObjects
+		*out = make([]RestoreFailedObject, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoresStatus.
@@ -203,13 +445,151 @@ func (in *RestoresStatus) DeepCopy() *RestoresStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Schedules) DeepCopyInto(out *Schedules) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Schedules.
+func (in *Schedules) DeepCopy() *Schedules {
+	if in == nil {
+		return nil
+	}
+	out := new(Schedules)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Schedules) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulesList) DeepCopyInto(out *SchedulesList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Schedules, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulesList.
+func (in *SchedulesList) DeepCopy() *SchedulesList {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulesList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SchedulesList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulesSpec) DeepCopyInto(out *SchedulesSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulesSpec.
+func (in *SchedulesSpec) DeepCopy() *SchedulesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulesStatus) DeepCopyInto(out *SchedulesStatus) {
+	*out = *in
+	if in.ValidationErrors != nil {
+		in, out := &in.ValidationErrors, &out.ValidationErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastBackup != nil {
+		in, out := &in.LastBackup, &out.LastBackup
+		*out = (*in).DeepCopy()
+	}
+	if in.NextBackup != nil {
+		in, out := &in.NextBackup, &out.NextBackup
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulesStatus.
+func (in *SchedulesStatus) DeepCopy() *SchedulesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageLocationReference) DeepCopyInto(out *StorageLocationReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageLocationReference.
+func (in *StorageLocationReference) DeepCopy() *StorageLocationReference {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageLocationReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageLocationSpecConfig) DeepCopyInto(out *StorageLocationSpecConfig) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(CredentialsSecretRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageLocationSpecConfig.
+func (in *StorageLocationSpecConfig) DeepCopy() *StorageLocationSpecConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageLocationSpecConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageLocations) DeepCopyInto(out *StorageLocations) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageLocations.
@@ -265,6 +645,16 @@ func (in *StorageLocationsList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageLocationsSpec) DeepCopyInto(out *StorageLocationsSpec) {
 	*out = *in
+	if in.ContainerSpec != nil {
+		in, out := &in.ContainerSpec, &out.ContainerSpec
+		*out = new(StoragePluginContainerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigSpec != nil {
+		in, out := &in.ConfigSpec, &out.ConfigSpec
+		*out = new(StoragePluginConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageLocationsSpec.
@@ -280,6 +670,22 @@ func (in *StorageLocationsSpec) DeepCopy() *StorageLocationsSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageLocationsStatus) DeepCopyInto(out *StorageLocationsStatus) {
 	*out = *in
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastValidationTime != nil {
+		in, out := &in.LastValidationTime, &out.LastValidationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageLocationsStatus.
@@ -291,3 +697,84 @@ func (in *StorageLocationsStatus) DeepCopy() *StorageLocationsStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoragePluginConfigSpec) DeepCopyInto(out *StoragePluginConfigSpec) {
+	*out = *in
+	if in.StorageConfig != nil {
+		in, out := &in.StorageConfig, &out.StorageConfig
+		*out = new(StorageLocationSpecConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoragePluginConfigSpec.
+func (in *StoragePluginConfigSpec) DeepCopy() *StoragePluginConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StoragePluginConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoragePluginContainerSpec) DeepCopyInto(out *StoragePluginContainerSpec) {
+	*out = *in
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]corev1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]corev1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoragePluginContainerSpec.
+func (in *StoragePluginContainerSpec) DeepCopy() *StoragePluginContainerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StoragePluginContainerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
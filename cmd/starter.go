@@ -6,11 +6,13 @@ import (
 	"github.io/misskaori/boxroom-crd/global"
 	"github.io/misskaori/boxroom-crd/internal/controller"
 	k8s_agent "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-agent"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"os"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sync"
@@ -25,6 +27,8 @@ func (s *Starter) Init() error {
 		//AccessType: k8s_agent.InClusterConfigType,
 		AccessType:       k8s_agent.KubeConfigFileType,
 		KubernetesConfig: os.Getenv("KUBECONFIG"),
+		// the default exclusions of the backups, usually mounted from the default-filters ConfigMap
+		DefaultFiltersConfig: os.Getenv("DEFAULT_FILTERS_CONFIG"),
 	}).AgentInit()
 
 	if err != nil {
@@ -68,6 +72,10 @@ func (s *Starter) RunReconciler() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "276f5320.io",
+		// the credentials secrets are read from the api server, caching them would cache every secret in the cluster
+		Client: client.Options{
+			Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}}},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -95,6 +103,35 @@ func (s *Starter) RunReconciler() {
 		setupLog.Error(err, "unable to create controller", "controller", "Restores")
 		os.Exit(1)
 	}
+	if err = (&controller.SchedulesReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Schedules")
+		os.Exit(1)
+	}
+	if err = (&controller.BackupsGarbageCollector{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Frequency: controller.DefaultGarbageCollectionFrequency,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BackupsGarbageCollector")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&boxroomv1.StorageLocations{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "StorageLocations")
+			os.Exit(1)
+		}
+		if err = (&boxroomv1.Backups{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Backups")
+			os.Exit(1)
+		}
+		if err = (&boxroomv1.Restores{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Restores")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,123 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+	"github.io/misskaori/boxroom-crd/global"
+	"github.io/misskaori/boxroom-crd/kubernetes/controller"
+	"github.io/misskaori/boxroom-crd/kubernetes/storage/dir"
+	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
+	_ "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client/filesystem"
+	_ "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client/s3/s3-client"
+	_ "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client/s3/s3-rest-client"
+)
+
+// errStorageLocationUnavailable is returned for a storage location which does not exist or whose last validation has failed.
+var errStorageLocationUnavailable = errors.New("storagelocation is unavailable")
+
+// getAgentController builds the agent controller which backs up to or restores from the given storage location,
+// or from the default storage location when the name is empty. It refuses storage locations which are not available
+// and returns the storage location it has resolved. The caller closes the agent controller once it is done with it.
+func getAgentController(ctx context.Context, c client.Client, namespace, storageLocationName string) (*controller.AgentController, *boxroomv1.StorageLocations, error) {
+	storageLocation, err := boxroomv1.ResolveStorageLocation(ctx, c, namespace, storageLocationName)
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil, nil, fmt.Errorf("%w: storagelocation %s does not exist", errStorageLocationUnavailable, storageLocationName)
+	case errors.Is(err, boxroomv1.ErrNoDefaultStorageLocation):
+		return nil, nil, fmt.Errorf("%w: %v", errStorageLocationUnavailable, err)
+	case err != nil:
+		return nil, nil, err
+	}
+
+	if err = checkStorageLocationAvailable(storageLocation); err != nil {
+		return nil, storageLocation, err
+	}
+
+	storageClient, err := getStoreClient(ctx, c, storageLocation)
+	if err != nil {
+		return nil, storageLocation, err
+	}
+
+	return &controller.AgentController{
+		KubernetesAgent: global.KubernetesAgent,
+		StorageClient:   storageClient,
+		DirDefinition:   &dir.DefaultStorageDirDefinition{},
+	}, storageLocation, nil
+}
+
+// checkStorageLocationAvailable wraps errStorageLocationUnavailable when the validation of the storage location
+// has failed, a storage location which has not been validated yet gets a plain error so it is retried.
+func checkStorageLocationAvailable(storageLocation *boxroomv1.StorageLocations) error {
+	available := meta.FindStatusCondition(storageLocation.Status.Conditions, boxroomv1.StorageLocationConditionAvailable)
+	switch {
+	case available == nil || available.Status == metav1.ConditionUnknown:
+		return fmt.Errorf("storagelocation %s has not been validated yet", storageLocation.Name)
+	case available.Status == metav1.ConditionFalse:
+		return fmt.Errorf("%w: %s: %s", errStorageLocationUnavailable, storageLocation.Name, available.Message)
+	}
+	return nil
+}
+
+// getStoreClient builds the store client which the storage location chooses.
+func getStoreClient(ctx context.Context, c client.Client, storageLocation *boxroomv1.StorageLocations) (storeclient.StoreClient, error) {
+	var err error
+	settings := storageLocation.GetStoreClientSettings()
+	if storageLocation.GetCredentialsSecretRef() != nil {
+		settings.AccessKey, settings.SecretKey, err = getCredentials(ctx, c, storageLocation)
+		if err != nil {
+			return nil, fmt.Errorf("storagelocation %s: %w", storageLocation.Name, err)
+		}
+	}
+
+	storageClient, err := storeclient.NewStoreClient(storageLocation.GetClientKind(), settings)
+	if err != nil {
+		return nil, fmt.Errorf("storagelocation %s: %w", storageLocation.Name, err)
+	}
+	return storageClient, nil
+}
+
+// getCredentials reads the access key and the secret key from the credentials secret of the storage location.
+func getCredentials(ctx context.Context, c client.Client, storageLocation *boxroomv1.StorageLocations) (string, string, error) {
+	ref := storageLocation.GetCredentialsSecretRef()
+	secret := &v1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: storageLocation.Namespace, Name: ref.Name}, secret); err != nil {
+		return "", "", fmt.Errorf("can not get credentials secret %s: %w", ref.Name, err)
+	}
+
+	accessKey, exist := secret.Data[ref.GetAccessKey()]
+	if !exist {
+		return "", "", fmt.Errorf("credentials secret %s has no key %s", ref.Name, ref.GetAccessKey())
+	}
+	secretKey, exist := secret.Data[ref.GetSecretKey()]
+	if !exist {
+		return "", "", fmt.Errorf("credentials secret %s has no key %s", ref.Name, ref.GetSecretKey())
+	}
+
+	return string(accessKey), string(secretKey), nil
+}
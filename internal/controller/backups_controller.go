@@ -18,13 +18,20 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+	k8s_agent "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-agent"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
+	storeagent "github.io/misskaori/boxroom-crd/kubernetes/storage/store-agent"
+	util_log "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
 )
 
 // BackupsReconciler reconciles a Backups object
@@ -37,21 +44,257 @@ type BackupsReconciler struct {
 //+kubebuilder:rbac:groups=boxroom.io,resources=backups/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=boxroom.io,resources=backups/finalizers,verbs=update
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the Backups object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.15.0/pkg/reconcile
+// Reconcile takes a backup of the cluster for every new Backups object.
+// The backup is described by the Backups spec and uploaded to the storage location it refers to,
+// its progress and result are written to the Backups status.
 func (r *BackupsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	backup := &boxroomv1.Backups{}
+	if err := r.Get(ctx, req.NamespacedName, backup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if backup.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
 
-	// TODO(user): your logic here
+	switch backup.Status.Phase {
+	case "":
+		backup.Status.Phase = boxroomv1.BackupPhaseNew
+		return ctrl.Result{}, r.updateBackupStatus(ctx, backup)
+	case boxroomv1.BackupPhaseNew:
+	case boxroomv1.BackupPhaseInProgress:
+		// the backup runs inside a single reconcile, so it has been interrupted if it is still in progress here
+		r.finishBackup(backup, nil, fmt.Errorf("backup %s was interrupted before completion", backup.Name))
+		return ctrl.Result{}, r.updateBackupStatus(ctx, backup)
+	default:
+		if backup.IsRestorable() && !backup.IsOrphaned() && len(backup.GetPendingReplicaStorageLocations()) != 0 {
+			return ctrl.Result{}, r.copyBackup(ctx, backup)
+		}
+		return ctrl.Result{}, nil
+	}
 
-	return ctrl.Result{}, nil
+	util_log.Logger.Infof("begin to handle backup: %v", backup.Name)
+	filters, err := getBackupFilters(backup)
+	if err != nil {
+		util_log.Logger.Error(err)
+		r.finishBackup(backup, nil, err)
+		return ctrl.Result{}, r.updateBackupStatus(ctx, backup)
+	}
+
+	agentController, storageLocation, err := getAgentController(ctx, r.Client, backup.Namespace, backup.Spec.StorageLocation)
+	if storageLocation != nil {
+		backup.Status.StorageLocation = storageLocation.GetReference()
+	}
+	if err != nil {
+		util_log.Logger.Error(err)
+		if errors.Is(err, errStorageLocationUnavailable) {
+			r.finishBackup(backup, nil, err)
+			return ctrl.Result{}, r.updateBackupStatus(ctx, backup)
+		}
+		return ctrl.Result{}, err
+	}
+	// the replicas are added to the agent controller, so closing it closes their store clients as well
+	defer agentController.Close()
+
+	replicas, err := r.getReplicaTargets(ctx, backup)
+	if err != nil {
+		util_log.Logger.Error(err)
+		return ctrl.Result{}, err
+	}
+	for _, replica := range replicas {
+		agentController.Replicas = append(agentController.Replicas, replica.target)
+	}
+
+	root := getBackupResourceTree(backup)
+	exist, err := agentController.CheckRemoteTreeExist(root)
+	if err != nil {
+		util_log.Logger.Error(err)
+		return ctrl.Result{}, err
+	}
+	if exist {
+		r.finishBackup(backup, nil, fmt.Errorf("the backup tree %s already exists in storagelocation %s", root.TreeName, storageLocation.Name))
+		return ctrl.Result{}, r.updateBackupStatus(ctx, backup)
+	}
+
+	now := metav1.Now()
+	backup.Status.Phase = boxroomv1.BackupPhaseInProgress
+	backup.Status.StartTimestamp = &now
+	backup.Status.TreeName = root.TreeName
+	if err = r.updateBackupStatus(ctx, backup); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	missionStatus, err := agentController.Backup(root, filters)
+	if err != nil {
+		util_log.Logger.Error(err)
+	}
+	backup.Status.TreeName = root.TreeName
+	r.finishBackup(backup, missionStatus, err)
+	r.finishStorageLocations(backup, replicas)
+	util_log.Logger.Infof("backup %v is finished: phase: %v tree name: %v", backup.Name, backup.Status.Phase, root.TreeName)
+
+	return ctrl.Result{}, r.updateBackupStatus(ctx, backup)
+}
+
+// replicaTarget is a replica storage location of a backup together with the storage which it is written to.
+type replicaTarget struct {
+	ref    boxroomv1.StorageLocationReference
+	target *storeagent.StoreTarget
+}
+
+// getReplicaTargets builds the storages of the replica storage locations which the backup has not been written to yet,
+// a replica storage location which is unavailable gets a failed status entry instead.
+func (r *BackupsReconciler) getReplicaTargets(ctx context.Context, backup *boxroomv1.Backups) ([]replicaTarget, error) {
+	var replicas []replicaTarget
+	for _, ref := range backup.GetPendingReplicaStorageLocations() {
+		agentController, _, err := getAgentController(ctx, r.Client, ref.Namespace, ref.Name)
+		if errors.Is(err, errStorageLocationUnavailable) {
+			backup.SetStorageLocationStatus(newBackupStorageLocationStatus(ref, false, err))
+			continue
+		}
+		if err != nil {
+			for _, replica := range replicas {
+				_ = replica.target.Client.Close()
+			}
+			return nil, err
+		}
+
+		replicas = append(replicas, replicaTarget{
+			ref:    ref,
+			target: &storeagent.StoreTarget{Name: ref.String(), Client: agentController.StorageClient},
+		})
+	}
+	return replicas, nil
+}
+
+// copyBackup replicates a finished backup from its storage location to the replica storage locations
+// which have been added after it has finished.
+func (r *BackupsReconciler) copyBackup(ctx context.Context, backup *boxroomv1.Backups) error {
+	source := backup.GetStorageLocationReference()
+	agentController, _, err := getAgentController(ctx, r.Client, source.Namespace, source.Name)
+	if errors.Is(err, errStorageLocationUnavailable) {
+		util_log.Logger.Error(err)
+		for _, ref := range backup.GetPendingReplicaStorageLocations() {
+			backup.SetStorageLocationStatus(newBackupStorageLocationStatus(ref, false, err))
+		}
+		return r.updateBackupStatus(ctx, backup)
+	}
+	if err != nil {
+		util_log.Logger.Error(err)
+		return err
+	}
+	defer agentController.Close()
+
+	replicas, err := r.getReplicaTargets(ctx, backup)
+	if err != nil {
+		util_log.Logger.Error(err)
+		return err
+	}
+	for _, replica := range replicas {
+		agentController.Replicas = append(agentController.Replicas, replica.target)
+	}
+
+	root := getBackupResourceTree(backup)
+	root.TreeName = backup.Status.TreeName
+	for _, replica := range replicas {
+		util_log.Logger.Infof("begin to copy backup: %v tree name: %v to storagelocation: %v", backup.Name, root.TreeName, replica.ref)
+		replica.target.Err = agentController.CopyRemoteTree(root, replica.target.Client)
+		backup.SetStorageLocationStatus(newBackupStorageLocationStatus(replica.ref, false, replica.target.Err))
+	}
+
+	return r.updateBackupStatus(ctx, backup)
+}
+
+// finishStorageLocations records the result of a backup for its storage location and its replica storage locations,
+// the replicas are only written once the backup has been uploaded to its storage location.
+func (r *BackupsReconciler) finishStorageLocations(backup *boxroomv1.Backups, replicas []replicaTarget) {
+	if backup.Status.StorageLocation == nil {
+		return
+	}
+
+	var err error
+	if !backup.IsRestorable() {
+		err = fmt.Errorf("backup %s is %s", backup.Name, backup.Status.Phase)
+	}
+	backup.SetStorageLocationStatus(newBackupStorageLocationStatus(*backup.Status.StorageLocation, true, err))
+
+	for _, replica := range replicas {
+		replicaErr := replica.target.Err
+		if err != nil {
+			replicaErr = err
+		}
+		backup.SetStorageLocationStatus(newBackupStorageLocationStatus(replica.ref, false, replicaErr))
+	}
+}
+
+func newBackupStorageLocationStatus(ref boxroomv1.StorageLocationReference, primary bool, err error) boxroomv1.BackupStorageLocationStatus {
+	now := metav1.Now()
+	locationStatus := boxroomv1.BackupStorageLocationStatus{
+		StorageLocationReference: ref,
+		Primary:                  primary,
+		Phase:                    boxroomv1.BackupPhaseCompleted,
+		CompletionTimestamp:      &now,
+	}
+	if err != nil {
+		locationStatus.Phase = boxroomv1.BackupPhaseFailed
+		locationStatus.Message = err.Error()
+	}
+	return locationStatus
+}
+
+// finishBackup moves a backup to its final phase according to the mission status reported by the agent controller.
+func (r *BackupsReconciler) finishBackup(backup *boxroomv1.Backups, missionStatus tree.Status, err error) {
+	now := metav1.Now()
+	backup.Status.CompletionTimestamp = &now
+	if backup.Spec.TTL != nil {
+		backup.Status.Expiration = &metav1.Time{Time: now.Add(backup.Spec.TTL.Duration)}
+	}
+
+	if missionStatus != nil {
+		backup.Status.ItemsBackedUp = missionStatus.GetSucceededObjects()
+		backup.Status.ItemsFailed = len(missionStatus.GetFailedObjects())
+	}
+
+	switch {
+	case err != nil || missionStatus == nil || missionStatus.GetStatus() == k8s_agent.StatusFailed:
+		backup.Status.Phase = boxroomv1.BackupPhaseFailed
+	case missionStatus.GetStatus() == k8s_agent.StatusPartialFailed:
+		backup.Status.Phase = boxroomv1.BackupPhasePartiallyFailed
+	default:
+		backup.Status.Phase = boxroomv1.BackupPhaseCompleted
+	}
+
+	message := "backup is completed"
+	if err != nil {
+		message = err.Error()
+	}
+
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type:               boxroomv1.BackupConditionCompleted,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: backup.Generation,
+		Reason:             string(backup.Status.Phase),
+		Message:            message,
+	})
+
+	succeeded := metav1.ConditionFalse
+	if backup.Status.Phase == boxroomv1.BackupPhaseCompleted {
+		succeeded = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type:               boxroomv1.BackupConditionSucceeded,
+		Status:             succeeded,
+		ObservedGeneration: backup.Generation,
+		Reason:             string(backup.Status.Phase),
+		Message:            message,
+	})
+}
+
+func (r *BackupsReconciler) updateBackupStatus(ctx context.Context, backup *boxroomv1.Backups) error {
+	if err := r.Status().Update(ctx, backup); err != nil {
+		util_log.Logger.Error(err)
+		return err
+	}
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
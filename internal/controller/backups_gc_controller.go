@@ -0,0 +1,207 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+	util_log "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
+)
+
+// DefaultGarbageCollectionFrequency is how often finished backups are checked against the retention of their schedule.
+const DefaultGarbageCollectionFrequency = time.Hour
+
+// BackupsGarbageCollector removes expired backups from their storage location and deletes their Backups object.
+// A backup is expired once its TTL has passed, or once its schedule keeps enough newer restorable backups.
+type BackupsGarbageCollector struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Frequency time.Duration
+}
+
+//+kubebuilder:rbac:groups=boxroom.io,resources=backups,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups=boxroom.io,resources=schedules,verbs=get;list;watch
+
+// Reconcile garbage collects a finished backup if it is expired, otherwise it is checked again
+// when its TTL ends or after the garbage collection frequency, whichever comes first.
+func (r *BackupsGarbageCollector) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	backup := &boxroomv1.Backups{}
+	if err := r.Get(ctx, req.NamespacedName, backup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if backup.DeletionTimestamp != nil || !backup.IsFinished() {
+		return ctrl.Result{}, nil
+	}
+
+	frequency := r.Frequency
+	if frequency == 0 {
+		frequency = DefaultGarbageCollectionFrequency
+	}
+
+	expired, requeueAfter := r.checkExpiration(backup, frequency)
+	if !expired {
+		pruned, err := r.checkScheduleRetention(ctx, backup)
+		if err != nil {
+			util_log.Logger.Error(err)
+			return ctrl.Result{}, err
+		}
+		if !pruned {
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+	}
+
+	util_log.Logger.Infof("begin to garbage collect backup: %v tree name: %v", backup.Name, backup.Status.TreeName)
+	if err := r.deleteRemoteTree(ctx, backup); err != nil {
+		util_log.Logger.Error(err)
+		return ctrl.Result{}, err
+	}
+
+	if err := r.Delete(ctx, backup); err != nil {
+		util_log.Logger.Error(err)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	util_log.Logger.Infof("backup %v is garbage collected", backup.Name)
+
+	return ctrl.Result{}, nil
+}
+
+func (r *BackupsGarbageCollector) checkExpiration(backup *boxroomv1.Backups, frequency time.Duration) (bool, time.Duration) {
+	if backup.Status.Expiration == nil {
+		return false, frequency
+	}
+
+	untilExpiration := time.Until(backup.Status.Expiration.Time)
+	if untilExpiration <= 0 {
+		return true, 0
+	}
+	if untilExpiration < frequency {
+		return false, untilExpiration
+	}
+	return false, frequency
+}
+
+// checkScheduleRetention tells whether a scheduled backup is pruned by the keepLast policy of its schedule,
+// which is the case when the schedule already keeps enough restorable backups that are newer than it.
+func (r *BackupsGarbageCollector) checkScheduleRetention(ctx context.Context, backup *boxroomv1.Backups) (bool, error) {
+	scheduleName, ok := backup.Labels[boxroomv1.ScheduleNameLabel]
+	if !ok {
+		return false, nil
+	}
+
+	schedule := &boxroomv1.Schedules{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: backup.Namespace, Name: scheduleName}, schedule); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if schedule.Spec.KeepLast <= 0 {
+		return false, nil
+	}
+
+	backupList := &boxroomv1.BackupsList{}
+	if err := r.List(ctx, backupList, client.InNamespace(backup.Namespace), client.MatchingLabels{boxroomv1.ScheduleNameLabel: scheduleName}); err != nil {
+		return false, err
+	}
+
+	newerBackups := 0
+	for _, other := range backupList.Items {
+		if other.IsRestorable() && backup.CreationTimestamp.Before(&other.CreationTimestamp) {
+			newerBackups++
+		}
+	}
+
+	return newerBackups >= schedule.Spec.KeepLast, nil
+}
+
+func (r *BackupsGarbageCollector) deleteRemoteTree(ctx context.Context, backup *boxroomv1.Backups) error {
+	// a backup which never started has not uploaded anything, its tree name may even belong to another backup
+	if backup.Status.StartTimestamp == nil || len(backup.Status.TreeName) == 0 {
+		return nil
+	}
+
+	root := getBackupResourceTree(backup)
+	root.TreeName = backup.Status.TreeName
+
+	for _, locationStatus := range backup.Status.StorageLocations {
+		if locationStatus.Primary || locationStatus.Phase != boxroomv1.BackupPhaseCompleted {
+			continue
+		}
+		agentController, _, err := getAgentController(ctx, r.Client, locationStatus.Namespace, locationStatus.Name)
+		if errors.Is(err, errStorageLocationUnavailable) {
+			orphaned, orphanErr := r.isReplicaOrphaned(ctx, locationStatus.StorageLocationReference)
+			if orphanErr != nil {
+				return orphanErr
+			}
+			if orphaned {
+				util_log.Logger.Infof("backup %v: tree is left in replica storagelocation %v: %v", backup.Name, locationStatus.Name, err)
+				continue
+			}
+		}
+		if err != nil {
+			return err
+		}
+		err = agentController.DeleteRemoteTree(root)
+		_ = agentController.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	// the storage location of an orphaned backup is gone, its tree is left in the storage
+	if backup.IsOrphaned() {
+		return nil
+	}
+
+	storageLocation := backup.GetStorageLocationReference()
+	agentController, _, err := getAgentController(ctx, r.Client, storageLocation.Namespace, storageLocation.Name)
+	if err != nil {
+		return err
+	}
+	defer agentController.Close()
+
+	return agentController.DeleteRemoteTree(root)
+}
+
+// isReplicaOrphaned tells whether the tree of a replica which can not be reached is given up, which is the case when
+// its StorageLocations object is gone or orphans its backups. Any other replica is retried until it is available
+// again, so its tree is not left behind without a record.
+func (r *BackupsGarbageCollector) isReplicaOrphaned(ctx context.Context, ref boxroomv1.StorageLocationReference) (bool, error) {
+	storageLocation := &boxroomv1.StorageLocations{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, storageLocation)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return storageLocation.Spec.DeletionPolicy == boxroomv1.StorageLocationDeletionPolicyOrphan, nil
+}
+
+// SetupWithManager sets up the garbage collector with the Manager.
+func (r *BackupsGarbageCollector) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("backups-gc").
+		For(&boxroomv1.Backups{}).
+		Complete(r)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+)
+
+// newUnuploadedBackup builds a backup in the phase which has not uploaded a tree, so it is garbage collected without a storage.
+func newUnuploadedBackup(name string, created time.Time, phase boxroomv1.BackupPhase) *boxroomv1.Backups {
+	return &boxroomv1.Backups{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, CreationTimestamp: metav1.NewTime(created)},
+		Status:     boxroomv1.BackupsStatus{Phase: phase},
+	}
+}
+
+func reconcileBackupGC(t *testing.T, r *BackupsGarbageCollector, name string) ctrl.Result {
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func backupExists(t *testing.T, c client.Client, name string) bool {
+	err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &boxroomv1.Backups{})
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
+
+func TestGarbageCollectByTTL(t *testing.T) {
+	now := time.Now()
+	expired := newUnuploadedBackup("expired", now.Add(-2*time.Hour), boxroomv1.BackupPhaseCompleted)
+	expired.Status.Expiration = &metav1.Time{Time: now.Add(-time.Minute)}
+	expiring := newUnuploadedBackup("expiring", now.Add(-2*time.Hour), boxroomv1.BackupPhaseCompleted)
+	expiring.Status.Expiration = &metav1.Time{Time: now.Add(10 * time.Minute)}
+	forever := newUnuploadedBackup("forever", now.Add(-2*time.Hour), boxroomv1.BackupPhaseCompleted)
+	running := newUnuploadedBackup("running", now.Add(-2*time.Hour), boxroomv1.BackupPhaseInProgress)
+	running.Status.Expiration = &metav1.Time{Time: now.Add(-time.Minute)}
+
+	c := newFakeClientBuilder(t).WithObjects(expired, expiring, forever, running).Build()
+	r := &BackupsGarbageCollector{Client: c, Scheme: c.Scheme()}
+
+	reconcileBackupGC(t, r, "expired")
+	if backupExists(t, c, "expired") {
+		t.Errorf("backup expired is kept after its TTL")
+	}
+
+	if result := reconcileBackupGC(t, r, "expiring"); result.RequeueAfter <= 0 || result.RequeueAfter > 10*time.Minute {
+		t.Errorf("backup expiring is checked again after %v, want when its TTL ends", result.RequeueAfter)
+	}
+	if result := reconcileBackupGC(t, r, "forever"); result.RequeueAfter != DefaultGarbageCollectionFrequency {
+		t.Errorf("backup forever is checked again after %v, want %v", result.RequeueAfter, DefaultGarbageCollectionFrequency)
+	}
+	reconcileBackupGC(t, r, "running")
+	for _, name := range []string{"expiring", "forever", "running"} {
+		if !backupExists(t, c, name) {
+			t.Errorf("backup %s is garbage collected", name)
+		}
+	}
+}
+
+func TestGarbageCollectByKeepLast(t *testing.T) {
+	now := time.Now()
+	schedule := &boxroomv1.Schedules{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hourly"},
+		Spec:       boxroomv1.SchedulesSpec{Schedule: "0 * * * *", KeepLast: 2},
+	}
+	// the failed backup is the newest one, it does not count towards the kept backups
+	backups := []*boxroomv1.Backups{
+		newUnuploadedBackup("hourly-1", now.Add(-4*time.Hour), boxroomv1.BackupPhaseCompleted),
+		newUnuploadedBackup("hourly-2", now.Add(-3*time.Hour), boxroomv1.BackupPhaseCompleted),
+		newUnuploadedBackup("hourly-3", now.Add(-2*time.Hour), boxroomv1.BackupPhasePartiallyFailed),
+		newUnuploadedBackup("hourly-4", now.Add(-time.Hour), boxroomv1.BackupPhaseFailed),
+	}
+	builder := newFakeClientBuilder(t).WithObjects(schedule)
+	for _, backup := range backups {
+		backup.Labels = map[string]string{boxroomv1.ScheduleNameLabel: schedule.Name}
+		builder = builder.WithObjects(backup)
+	}
+	c := builder.Build()
+	r := &BackupsGarbageCollector{Client: c, Scheme: c.Scheme()}
+
+	for _, backup := range backups {
+		reconcileBackupGC(t, r, backup.Name)
+	}
+
+	if backupExists(t, c, "hourly-1") {
+		t.Errorf("backup hourly-1 is kept although its schedule keeps two newer backups")
+	}
+	for _, name := range []string{"hourly-2", "hourly-3", "hourly-4"} {
+		if !backupExists(t, c, name) {
+			t.Errorf("backup %s is pruned, want the last two restorable backups and the newer failed one kept", name)
+		}
+	}
+}
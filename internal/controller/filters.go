@@ -0,0 +1,132 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	mapset "github.com/deckarep/golang-set"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+	k8sfilter "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-filter"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
+)
+
+// getBackupResourceTree builds the root of the resource tree which the backup is written to.
+func getBackupResourceTree(backup *boxroomv1.Backups) *tree.KubernetesRoot {
+	treeName := backup.Spec.TreeName
+	if len(treeName) == 0 {
+		treeName = backup.Name
+	}
+	return getResourceTree(treeName)
+}
+
+// getResourceTree builds the root of the backup resource tree with the given name.
+func getResourceTree(treeName string) *tree.KubernetesRoot {
+	return &tree.KubernetesRoot{
+		Kind:     immobile.RootKind,
+		Name:     immobile.RootName,
+		TreeKind: immobile.TreeBackupKind,
+		TreeName: treeName,
+		Parent:   nil,
+		Groups:   map[string]*tree.Group{},
+	}
+}
+
+// getBackupFilters converts the filters of the backup spec into the filters of the kubernetes agent,
+// the default filters of the backup override the default exclusions of the agent.
+func getBackupFilters(backup *boxroomv1.Backups) (map[string]tree.Filter, error) {
+	filters, err := getResourceFilters(&backup.Spec.ResourceFilterSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Spec.DefaultFilters != nil {
+		filters[immobile.DefaultFiltersKind] = &k8sfilter.DefaultFilters{
+			ExcludedNamespaces:       backup.Spec.DefaultFilters.ExcludedNamespaces,
+			ExcludedResources:        backup.Spec.DefaultFilters.ExcludedResources,
+			IncludedClusterResources: backup.Spec.DefaultFilters.IncludedClusterResources,
+			ExcludedObjects:          backup.Spec.DefaultFilters.ExcludedObjects,
+		}
+	}
+	return filters, nil
+}
+
+// getRestoreFilters converts the filters of the restore spec into the filters of the storage agent.
+func getRestoreFilters(restore *boxroomv1.Restores) (map[string]tree.Filter, error) {
+	return getResourceFilters(&restore.Spec.ResourceFilterSpec)
+}
+
+func getResourceFilters(spec *boxroomv1.ResourceFilterSpec) (map[string]tree.Filter, error) {
+	filters := map[string]tree.Filter{}
+
+	if filter := getFilter(immobile.NamespaceKind, spec.IncludedNamespaces, spec.ExcludedNamespaces); filter != nil {
+		filters[immobile.NamespaceKind] = filter
+	}
+	if filter := getFilter(immobile.ResourceKind, spec.IncludedResources, spec.ExcludedResources); filter != nil {
+		filters[immobile.ResourceKind] = filter
+	}
+	filters[immobile.ClusterKind] = k8sfilter.GetClusterResourceFilter(spec.IncludeClusterResources)
+	if spec.LabelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		filters[immobile.LabelSelectorKind] = k8sfilter.GetLabelSelectorFilter(selector)
+	}
+	if len(spec.ObjectFilters) != 0 {
+		resourceFilters := map[string]*k8sfilter.KubernetesResourceFilter{}
+		for _, objectFilter := range spec.ObjectFilters {
+			if filter := getFilter(immobile.ObjectKind, objectFilter.IncludedNames, objectFilter.ExcludedNames); filter != nil {
+				resourceFilters[objectFilter.Resource] = filter
+			}
+		}
+		filters[immobile.ObjectFilterKind] = k8sfilter.GetObjectFilter(resourceFilters)
+	}
+
+	return filters, nil
+}
+
+// getFilter merges the included and excluded names into one filter, the excluded names become the exceptions
+// of the included ones when both of them are given, so they can narrow down an included pattern.
+func getFilter(kind string, included, excluded []string) *k8sfilter.KubernetesResourceFilter {
+	if len(included) == 0 && len(excluded) == 0 {
+		return nil
+	}
+
+	filter := &k8sfilter.KubernetesResourceFilter{
+		Kind:              kind,
+		ResourceInclude:   len(included) != 0,
+		ResourceFilterSet: mapset.NewSet(),
+	}
+
+	if filter.ResourceInclude {
+		for _, name := range included {
+			filter.ResourceFilterSet.Add(name)
+		}
+		filter.ExcludeFilterSet = mapset.NewSet()
+		for _, name := range excluded {
+			filter.ExcludeFilterSet.Add(name)
+		}
+	} else {
+		for _, name := range excluded {
+			filter.ResourceFilterSet.Add(name)
+		}
+	}
+
+	return filter
+}
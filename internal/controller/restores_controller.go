@@ -18,13 +18,23 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+	"github.io/misskaori/boxroom-crd/kubernetes/controller"
+	k8s_agent "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-agent"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
+	util_log "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
 )
 
 // RestoresReconciler reconciles a Restores object
@@ -37,21 +47,173 @@ type RestoresReconciler struct {
 //+kubebuilder:rbac:groups=boxroom.io,resources=restores/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=boxroom.io,resources=restores/finalizers,verbs=update
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the Restores object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.15.0/pkg/reconcile
+// Reconcile restores a backup into the cluster for every new Restores object.
+// The backup tree is downloaded from the storage location, filtered by the Restores spec and applied,
+// the outcome is written to the Restores status.
 func (r *RestoresReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	restore := &boxroomv1.Restores{}
+	if err := r.Get(ctx, req.NamespacedName, restore); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if restore.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
 
-	// TODO(user): your logic here
+	switch restore.Status.Phase {
+	case "":
+		restore.Status.Phase = boxroomv1.RestorePhaseNew
+		return ctrl.Result{}, r.updateRestoreStatus(ctx, restore)
+	case boxroomv1.RestorePhaseNew:
+	case boxroomv1.RestorePhaseInProgress:
+		// the restore runs inside a single reconcile, so it has been interrupted if it is still in progress here
+		r.finishRestore(restore, nil, fmt.Errorf("restore %s was interrupted before completion", restore.Name))
+		return ctrl.Result{}, r.updateRestoreStatus(ctx, restore)
+	default:
+		return ctrl.Result{}, nil
+	}
 
-	return ctrl.Result{}, nil
+	util_log.Logger.Infof("begin to handle restore: %v", restore.Name)
+	filters, err := getRestoreFilters(restore)
+	if err != nil {
+		util_log.Logger.Error(err)
+		r.finishRestore(restore, nil, err)
+		return ctrl.Result{}, r.updateRestoreStatus(ctx, restore)
+	}
+
+	backupTreeName, storageLocationRef, err := r.getRestoreSource(ctx, restore)
+	if err != nil {
+		util_log.Logger.Error(err)
+		r.finishRestore(restore, nil, err)
+		return ctrl.Result{}, r.updateRestoreStatus(ctx, restore)
+	}
+
+	agentController, storageLocation, err := getAgentController(ctx, r.Client, storageLocationRef.Namespace, storageLocationRef.Name)
+	if storageLocation != nil {
+		restore.Status.StorageLocation = storageLocation.GetReference()
+	}
+	if err != nil {
+		util_log.Logger.Error(err)
+		if errors.Is(err, errStorageLocationUnavailable) {
+			r.finishRestore(restore, nil, err)
+			return ctrl.Result{}, r.updateRestoreStatus(ctx, restore)
+		}
+		return ctrl.Result{}, err
+	}
+	defer agentController.Close()
+
+	now := metav1.Now()
+	restore.Status.Phase = boxroomv1.RestorePhaseInProgress
+	restore.Status.StartTimestamp = &now
+	restore.Status.BackupTreeName = backupTreeName
+	if err = r.updateRestoreStatus(ctx, restore); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	root := getResourceTree(backupTreeName)
+	missionStatus, err := agentController.Restore(root, filters)
+	if err != nil {
+		util_log.Logger.Error(err)
+	} else {
+		restore.Status.TreeName = root.TreeName
+	}
+	// a failed restore has uploaded its log as well once root has been named after the restore tree
+	if root.TreeKind == immobile.TreeRestoreKind && !errors.Is(err, controller.ErrRestoreLoggerNotUploaded) {
+		restore.Status.LogLocation, _ = agentController.DirDefinition.GetAssistLogRemoteDir(root)
+	}
+	r.finishRestore(restore, missionStatus, err)
+	util_log.Logger.Infof("restore %v is finished: phase: %v backup tree name: %v", restore.Name, restore.Status.Phase, backupTreeName)
+
+	return ctrl.Result{}, r.updateRestoreStatus(ctx, restore)
+}
+
+// getRestoreSource resolves the remote backup tree and the storage location which a restore reads from,
+// a storage location without a name is resolved to the default storage location.
+func (r *RestoresReconciler) getRestoreSource(ctx context.Context, restore *boxroomv1.Restores) (string, boxroomv1.StorageLocationReference, error) {
+	storageLocation := boxroomv1.StorageLocationReference{Namespace: restore.Namespace, Name: restore.Spec.StorageLocation}
+	if len(restore.Spec.BackupName) == 0 {
+		if len(restore.Spec.TreeName) == 0 {
+			return "", storageLocation, fmt.Errorf("restore %s must refer to a backup or to a tree name", restore.Name)
+		}
+		return restore.Spec.TreeName, storageLocation, nil
+	}
+
+	backup := &boxroomv1.Backups{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: restore.Namespace, Name: restore.Spec.BackupName}, backup); err != nil {
+		return "", storageLocation, err
+	}
+	if !backup.IsRestorable() {
+		return "", storageLocation, fmt.Errorf("backup %s can not be restored in phase %q", backup.Name, backup.Status.Phase)
+	}
+
+	if len(storageLocation.Name) == 0 {
+		storageLocation = backup.GetStorageLocationReference()
+	}
+
+	return backup.Status.TreeName, storageLocation, nil
+}
+
+// finishRestore moves a restore to its final phase according to the mission status reported by the agent controller.
+func (r *RestoresReconciler) finishRestore(restore *boxroomv1.Restores, missionStatus tree.Status, err error) {
+	now := metav1.Now()
+	restore.Status.CompletionTimestamp = &now
+
+	if missionStatus != nil {
+		restore.Status.ItemsRestored = missionStatus.GetSucceededObjects()
+		restore.Status.ItemsFailed = len(missionStatus.GetFailedObjects())
+		restore.Status.FailedObjects = nil
+		for name, objectErr := range missionStatus.GetFailedObjects() {
+			failedObject := boxroomv1.RestoreFailedObject{Name: name}
+			if objectErr != nil {
+				failedObject.Error = objectErr.Error()
+			}
+			restore.Status.FailedObjects = append(restore.Status.FailedObjects, failedObject)
+		}
+		sort.Slice(restore.Status.FailedObjects, func(i, j int) bool {
+			return restore.Status.FailedObjects[i].Name < restore.Status.FailedObjects[j].Name
+		})
+	}
+
+	switch {
+	case err != nil || missionStatus == nil || missionStatus.GetStatus() == k8s_agent.StatusFailed:
+		restore.Status.Phase = boxroomv1.RestorePhaseFailed
+	case missionStatus.GetStatus() == k8s_agent.StatusPartialFailed:
+		restore.Status.Phase = boxroomv1.RestorePhasePartiallyFailed
+	default:
+		restore.Status.Phase = boxroomv1.RestorePhaseCompleted
+	}
+
+	message := "restore is completed"
+	if err != nil {
+		message = err.Error()
+	}
+
+	meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+		Type:               boxroomv1.RestoreConditionCompleted,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: restore.Generation,
+		Reason:             string(restore.Status.Phase),
+		Message:            message,
+	})
+
+	succeeded := metav1.ConditionFalse
+	if restore.Status.Phase == boxroomv1.RestorePhaseCompleted {
+		succeeded = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+		Type:               boxroomv1.RestoreConditionSucceeded,
+		Status:             succeeded,
+		ObservedGeneration: restore.Generation,
+		Reason:             string(restore.Status.Phase),
+		Message:            message,
+	})
+}
+
+func (r *RestoresReconciler) updateRestoreStatus(ctx context.Context, restore *boxroomv1.Restores) error {
+	if err := r.Status().Update(ctx, restore); err != nil {
+		util_log.Logger.Error(err)
+		return err
+	}
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
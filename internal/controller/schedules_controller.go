@@ -0,0 +1,130 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+	util_log "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
+)
+
+// SchedulesReconciler reconciles a Schedules object
+type SchedulesReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=boxroom.io,resources=schedules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=boxroom.io,resources=schedules/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=boxroom.io,resources=schedules/finalizers,verbs=update
+
+// Reconcile creates a Backups object from the schedule template every time the cron expression of a
+// Schedules object is due, the backup is timestamped with the due time. It requeues itself until the next run.
+func (r *SchedulesReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	schedule := &boxroomv1.Schedules{}
+	if err := r.Get(ctx, req.NamespacedName, schedule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if schedule.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+	oldStatus := schedule.Status.DeepCopy()
+
+	cronSchedule, err := cron.ParseStandard(schedule.Spec.Schedule)
+	if err != nil {
+		util_log.Logger.Errorf("schedule %v has an invalid cron expression: %v", schedule.Name, err)
+		schedule.Status.Phase = boxroomv1.SchedulePhaseFailedValidation
+		schedule.Status.ValidationErrors = []string{"invalid schedule: " + err.Error()}
+		schedule.Status.NextBackup = nil
+		return ctrl.Result{}, r.updateScheduleStatus(ctx, schedule, oldStatus)
+	}
+	schedule.Status.ValidationErrors = nil
+
+	if schedule.Spec.Paused {
+		schedule.Status.Phase = boxroomv1.SchedulePhasePaused
+		schedule.Status.NextBackup = nil
+		return ctrl.Result{}, r.updateScheduleStatus(ctx, schedule, oldStatus)
+	}
+	schedule.Status.Phase = boxroomv1.SchedulePhaseEnabled
+
+	lastBackup := schedule.CreationTimestamp.Time
+	if schedule.Status.LastBackup != nil {
+		lastBackup = schedule.Status.LastBackup.Time
+	}
+
+	now := time.Now()
+	nextBackup := cronSchedule.Next(lastBackup)
+	if nextBackup.After(now) {
+		schedule.Status.NextBackup = &metav1.Time{Time: nextBackup}
+		if err = r.updateScheduleStatus(ctx, schedule, oldStatus); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: nextBackup.Sub(now)}, nil
+	}
+
+	// the backup is named after the latest due time rather than the current time, so a reconcile which is
+	// retried after the status update has failed finds the backup it has already created. The runs which
+	// were missed before the latest one are skipped.
+	dueTime := nextBackup
+	for nextBackup = cronSchedule.Next(dueTime); !nextBackup.After(now); nextBackup = cronSchedule.Next(dueTime) {
+		dueTime = nextBackup
+	}
+
+	backup := schedule.GetBackup(dueTime)
+	util_log.Logger.Infof("schedule %v is due, begin to create backup: %v", schedule.Name, backup.Name)
+	if err = r.Create(ctx, backup); err != nil && !errors.IsAlreadyExists(err) {
+		util_log.Logger.Error(err)
+		return ctrl.Result{}, err
+	}
+
+	schedule.Status.LastBackup = &metav1.Time{Time: dueTime}
+	schedule.Status.LastBackupName = backup.Name
+	schedule.Status.NextBackup = &metav1.Time{Time: nextBackup}
+	if err = r.updateScheduleStatus(ctx, schedule, oldStatus); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: nextBackup.Sub(now)}, nil
+}
+
+func (r *SchedulesReconciler) updateScheduleStatus(ctx context.Context, schedule *boxroomv1.Schedules, oldStatus *boxroomv1.SchedulesStatus) error {
+	if reflect.DeepEqual(schedule.Status, *oldStatus) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, schedule); err != nil {
+		util_log.Logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SchedulesReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&boxroomv1.Schedules{}).
+		Complete(r)
+}
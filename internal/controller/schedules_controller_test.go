@@ -0,0 +1,130 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+	globleimmobile "github.io/misskaori/boxroom-crd/kubernetes/util/globle-immobile"
+)
+
+// newFakeClientBuilder builds the fake clients of the reconciler tests, the status of every kind is a subresource.
+func newFakeClientBuilder(t *testing.T) *fake.ClientBuilder {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := boxroomv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(
+		&boxroomv1.StorageLocations{}, &boxroomv1.Backups{}, &boxroomv1.Restores{}, &boxroomv1.Schedules{})
+}
+
+func TestScheduleRetryCreatesOneBackup(t *testing.T) {
+	created := time.Now().Add(-3 * time.Hour)
+	schedule := &boxroomv1.Schedules{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hourly", CreationTimestamp: metav1.NewTime(created)},
+		Spec:       boxroomv1.SchedulesSpec{Schedule: "0 * * * *"},
+	}
+
+	statusUpdates := 0
+	c := newFakeClientBuilder(t).WithObjects(schedule).WithInterceptorFuncs(interceptor.Funcs{
+		SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			statusUpdates++
+			if statusUpdates == 1 {
+				return errors.New("the status update has failed")
+			}
+			return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+		},
+	}).Build()
+	r := &SchedulesReconciler{Client: c, Scheme: c.Scheme()}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "hourly"}}
+	if _, err := r.Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected the failed status update to be returned")
+	}
+	result, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups := &boxroomv1.BackupsList{}
+	if err = c.List(context.Background(), backups); err != nil {
+		t.Fatal(err)
+	}
+	if len(backups.Items) != 1 {
+		t.Fatalf("%d backups were created for one due run, want 1", len(backups.Items))
+	}
+
+	now := time.Now()
+	dueTime := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location())
+	if name := "hourly-" + dueTime.Format(globleimmobile.TimestampFormat); backups.Items[0].Name != name {
+		t.Errorf("backup is named %s, want %s after the latest due time", backups.Items[0].Name, name)
+	}
+
+	if err = c.Get(context.Background(), req.NamespacedName, schedule); err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Status.LastBackup == nil || !schedule.Status.LastBackup.Time.Equal(dueTime) {
+		t.Errorf("last backup is %v, want the due time %v", schedule.Status.LastBackup, dueTime)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour {
+		t.Errorf("requeued after %v, want until the next run", result.RequeueAfter)
+	}
+}
+
+func TestScheduleNotDue(t *testing.T) {
+	schedule := &boxroomv1.Schedules{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "yearly", CreationTimestamp: metav1.Now()},
+		Spec:       boxroomv1.SchedulesSpec{Schedule: "@yearly"},
+	}
+	c := newFakeClientBuilder(t).WithObjects(schedule).Build()
+	r := &SchedulesReconciler{Client: c, Scheme: c.Scheme()}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "yearly"}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := &boxroomv1.BackupsList{}
+	if err := c.List(context.Background(), backups); err != nil {
+		t.Fatal(err)
+	}
+	if len(backups.Items) != 0 {
+		t.Errorf("%d backups were created before the schedule is due", len(backups.Items))
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, schedule); err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Status.Phase != boxroomv1.SchedulePhaseEnabled || schedule.Status.NextBackup == nil {
+		t.Errorf("status is %+v, want an enabled schedule with its next backup", schedule.Status)
+	}
+}
@@ -18,300 +18,513 @@ package controller
 
 import (
 	"context"
-	"github.com/golang/groupcache/lru"
+	"fmt"
 	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
 	util_log "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	"reflect"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // StorageLocationsReconciler reconciles a StorageLocations object
-var deletedPodCache = lru.New(1000)
-
 type StorageLocationsReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// ValidationFrequency is how often an available storage is validated, defaults to DefaultStorageLocationValidationFrequency.
+	ValidationFrequency time.Duration
 }
 
+const (
+	DefaultStorageLocationValidationFrequency = time.Minute
+	// storageLocationRetryFrequency is how often an unavailable storage is validated again.
+	storageLocationRetryFrequency = 10 * time.Second
+)
+
 //+kubebuilder:rbac:groups=boxroom.io,resources=storagelocations,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=boxroom.io,resources=storagelocations/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=boxroom.io,resources=storagelocations/finalizers,verbs=update
-
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the StorageLocations object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.15.0/pkg/reconcile
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=boxroom.io,resources=backups,verbs=get;list;watch
+//+kubebuilder:rbac:groups=boxroom.io,resources=backups/status,verbs=get;update;patch
+
+// Reconcile adds the finalizer to a StorageLocations object, runs the storage plugin of an rpc-plugin location
+// and validates the storage periodically, the result is written to the StorageLocations status.
+// A deleted StorageLocations object keeps its finalizer until its backups are handled by the deletion policy
+// and the storage plugin is torn down.
 func (r *StorageLocationsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	storageLocation, err := r.getStorageLocation(ctx, req)
+	if err != nil || storageLocation == nil {
+		return ctrl.Result{}, err
+	}
 	util_log.Logger.Infof("begin to handle storagelcoation: %v", storageLocation.Name)
-	if storageLocation == nil {
-		err = r.deleteBehaviour(ctx, req)
+	original := storageLocation.DeepCopy()
+
+	if storageLocation.DeletionTimestamp != nil {
+		requeueAfter, err := r.deleteBehaviour(ctx, storageLocation, original)
 		if err != nil {
 			util_log.Logger.Error(err)
 		}
-		return ctrl.Result{}, err
+		return ctrl.Result{RequeueAfter: requeueAfter}, err
+	}
+
+	if !controllerutil.ContainsFinalizer(storageLocation, boxroomv1.StorageLocationFinalizer) {
+		controllerutil.AddFinalizer(storageLocation, boxroomv1.StorageLocationFinalizer)
+		return ctrl.Result{}, r.Update(ctx, storageLocation)
 	}
 
-	if err = r.syncBehaviour(ctx, storageLocation); err != nil {
+	requeueAfter, err := r.syncBehaviour(ctx, storageLocation, original)
+	if err != nil {
 		util_log.Logger.Error(err)
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *StorageLocationsReconciler) getStorageLocation(ctx context.Context, req ctrl.Request) (*boxroomv1.StorageLocations, error) {
 	storageLocation := &boxroomv1.StorageLocations{}
 	if err := r.Get(ctx, req.NamespacedName, storageLocation); err != nil {
-		e := err.(errors.APIStatus)
-		if e.Status().Reason == metav1.StatusReasonNotFound {
+		if errors.IsNotFound(err) {
 			return nil, nil
 		}
-	}
-	if storageLocation.DeletionTimestamp != nil {
-		return nil, nil
+		return nil, err
 	}
 
 	return storageLocation, nil
 }
 
-func (r *StorageLocationsReconciler) deleteBehaviour(ctx context.Context, req ctrl.Request) error {
-	podList := &v1.PodList{}
-	if err := r.List(ctx, podList, client.InNamespace(req.Namespace)); err != nil {
-		return err
+// deleteBehaviour handles the backups of the storage location according to its deletion policy, then tears down
+// the service and the deployment of the storage plugin one after the other, and removes the finalizer at last.
+// It returns after how long the deletion has to be checked again while it is not done.
+func (r *StorageLocationsReconciler) deleteBehaviour(ctx context.Context, storageLocation, original *boxroomv1.StorageLocations) (time.Duration, error) {
+	if !controllerutil.ContainsFinalizer(storageLocation, boxroomv1.StorageLocationFinalizer) {
+		return 0, nil
 	}
 
-	for _, pod := range podList.Items {
-		if pod.OwnerReferences[0].Name == req.Name && pod.DeletionTimestamp == nil {
-			util_log.Logger.Infof("begin to delete pod: %v", pod.Name)
-			if err := r.deleteChildPod(ctx, &pod); err != nil {
-				return err
+	backups, err := r.getStorageLocationBackups(ctx, storageLocation)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(backups) != 0 {
+		storageLocation.Default()
+		switch storageLocation.Spec.DeletionPolicy {
+		case boxroomv1.StorageLocationDeletionPolicyOrphan:
+			util_log.Logger.Infof("storagelocation %v is deleted, begin to orphan %d backups", storageLocation.Name, len(backups))
+			if err = r.orphanBackups(ctx, storageLocation, backups); err != nil {
+				return 0, err
+			}
+		default:
+			var names []string
+			for _, backup := range backups {
+				names = append(names, backup.Name)
 			}
+			util_log.Logger.Infof("storagelocation %v is deleted, deletion is blocked by backups: %v", storageLocation.Name, names)
+			meta.SetStatusCondition(&storageLocation.Status.Conditions, metav1.Condition{
+				Type:               boxroomv1.StorageLocationConditionDeletionBlocked,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: storageLocation.Generation,
+				Reason:             "BackupsExist",
+				Message:            fmt.Sprintf("backups refer to the storagelocation: %s", strings.Join(names, ", ")),
+			})
+			return storageLocationRetryFrequency, r.updateStorageLocation(ctx, storageLocation, original)
 		}
 	}
 
-	return nil
+	for _, child := range getStoragePluginChildren(storageLocation) {
+		deleted, err := r.deleteChild(ctx, child)
+		if err != nil || !deleted {
+			return storageLocationRetryFrequency, err
+		}
+	}
+
+	util_log.Logger.Infof("storagelocation %v is cleaned up, begin to remove finalizer", storageLocation.Name)
+	controllerutil.RemoveFinalizer(storageLocation, boxroomv1.StorageLocationFinalizer)
+	return 0, r.Update(ctx, storageLocation)
 }
 
-func (r *StorageLocationsReconciler) syncBehaviour(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
-	util_log.Logger.Infof("begin to sync child pods: %v", storageLocation.Name)
-	err := r.syncChildPodList(ctx, storageLocation)
-	if err != nil {
-		return err
+// getStorageLocationBackups lists the backups which keep their tree in the storage location or replicate to it,
+// the backups of other namespaces refer to it when it is the cluster default.
+func (r *StorageLocationsReconciler) getStorageLocationBackups(ctx context.Context, storageLocation *boxroomv1.StorageLocations) ([]boxroomv1.Backups, error) {
+	backupList := &boxroomv1.BackupsList{}
+	if err := r.List(ctx, backupList); err != nil {
+		return nil, err
 	}
-	util_log.Logger.Infof("begin to sync child services: %v", storageLocation.Name)
-	err = r.syncChildService(ctx, storageLocation)
-	if err != nil {
-		return err
+
+	var backups []boxroomv1.Backups
+	for _, backup := range backupList.Items {
+		if backup.DeletionTimestamp == nil && backup.RefersToStorageLocation(*storageLocation.GetReference()) {
+			backups = append(backups, backup)
+		}
 	}
-	util_log.Logger.Infof("begin to update status: %v", storageLocation.Name)
-	err = r.updateStorageLocation(ctx, storageLocation)
-	return err
+	return backups, nil
+}
+
+// orphanBackups marks the backups whose tree is kept in the storage location as orphaned,
+// a storage location which is only a replica of a backup is marked as failed in the backup status.
+func (r *StorageLocationsReconciler) orphanBackups(ctx context.Context, storageLocation *boxroomv1.StorageLocations, backups []boxroomv1.Backups) error {
+	ref := *storageLocation.GetReference()
+	message := fmt.Sprintf("storagelocation %s was deleted, the backup tree is kept in the storage", storageLocation.Name)
+	for i := range backups {
+		backup := &backups[i]
+		if backup.GetStorageLocationReference() == ref {
+			meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+				Type:               boxroomv1.BackupConditionOrphaned,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: backup.Generation,
+				Reason:             "StorageLocationDeleted",
+				Message:            message,
+			})
+		} else {
+			backup.SetStorageLocationStatus(boxroomv1.BackupStorageLocationStatus{
+				StorageLocationReference: ref,
+				Phase:                    boxroomv1.BackupPhaseFailed,
+				Message:                  message,
+			})
+		}
+		if err := r.Status().Update(ctx, backup); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (r *StorageLocationsReconciler) syncChildPodList(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
-	childPodList, err := r.getStorageLocationChildPodList(ctx, storageLocation)
+// getStoragePluginChildren are the service and the deployment of the storage plugin, in the order they are torn down.
+func getStoragePluginChildren(storageLocation *boxroomv1.StorageLocations) []client.Object {
+	return []client.Object{
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: storageLocation.Namespace, Name: storageLocation.GetServiceName()}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: storageLocation.Namespace, Name: storageLocation.GetDeploymentName()}},
+	}
+}
 
+// deleteChild deletes a child of the storage location in the foreground and tells whether it is gone.
+func (r *StorageLocationsReconciler) deleteChild(ctx context.Context, child client.Object) (bool, error) {
+	err := r.Get(ctx, client.ObjectKeyFromObject(child), child)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	n := storageLocation.Spec.ContainerSpec.Replicas - len(childPodList)
-	createdOrDeletedChildPodNum := 0
-	for createdOrDeletedChildPodNum != n {
-		if n > 0 {
-			if err := r.createChildPod(ctx, storageLocation); err == nil {
-				createdOrDeletedChildPodNum++
-			}
+	if child.GetDeletionTimestamp() == nil {
+		util_log.Logger.Infof("begin to delete %T: %v", child, child.GetName())
+		if err = r.Delete(ctx, child, client.PropagationPolicy(metav1.DeletePropagationForeground)); err != nil {
+			return false, client.IgnoreNotFound(err)
 		}
+	}
+	return false, nil
+}
 
-		if n < 0 {
-			if err := r.deleteChildPod(ctx, &childPodList[0]); err == nil {
-				deletedPodCache.Add(childPodList[0].Name, "deleted")
-				childPodList = childPodList[1:]
-				createdOrDeletedChildPodNum--
-			}
+// syncBehaviour returns after how long the storage location has to be validated again.
+func (r *StorageLocationsReconciler) syncBehaviour(ctx context.Context, storageLocation, original *boxroomv1.StorageLocations) (time.Duration, error) {
+	// the defaulting webhook may be disabled, the plugin workload can not be built from a partial spec
+	storageLocation.Default()
+	util_log.Logger.Infof("begin to validate credentials: %v", storageLocation.Name)
+	r.syncCredentials(ctx, storageLocation)
+	if storageLocation.NeedsStoragePlugin() {
+		util_log.Logger.Infof("begin to sync child deployment: %v", storageLocation.Name)
+		if err := r.syncChildDeployment(ctx, storageLocation); err != nil {
+			return 0, err
+		}
+		util_log.Logger.Infof("begin to sync child services: %v", storageLocation.Name)
+		if err := r.syncChildService(ctx, storageLocation); err != nil {
+			return 0, err
 		}
+	} else if err := r.deleteStoragePlugin(ctx, storageLocation); err != nil {
+		return 0, err
 	}
+	util_log.Logger.Infof("begin to validate storage: %v", storageLocation.Name)
+	requeueAfter := r.syncAvailability(ctx, storageLocation)
+	util_log.Logger.Infof("begin to update status: %v", storageLocation.Name)
+	err := r.updateStorageLocation(ctx, storageLocation, original)
+	return requeueAfter, err
+}
 
-	childPodList, err = r.getStorageLocationChildPodList(ctx, storageLocation)
-	if err != nil {
-		return err
-	}
-	runningPodNum := 0
-	var endPoints []string
-	for _, pod := range childPodList {
-		if pod.Status.Phase == v1.PodRunning {
-			runningPodNum++
+// deleteStoragePlugin removes the service and the deployment of the storage plugin from a storage location whose
+// store client reaches the storage from the controller, they are left over when it used the plugin before.
+func (r *StorageLocationsReconciler) deleteStoragePlugin(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
+	for _, child := range getStoragePluginChildren(storageLocation) {
+		if _, err := r.deleteChild(ctx, child); err != nil {
+			return err
 		}
-		endPoints = append(endPoints, pod.Status.PodIP)
 	}
-	sort.Strings(endPoints)
-	storageLocation.Status.Replicas = runningPodNum
-	storageLocation.Status.Pods = endPoints
 
+	storageLocation.Status.Replicas = 0
+	storageLocation.Status.Pods = nil
+	storageLocation.Status.Service = ""
+	storageLocation.Status.ServiceIp = ""
+	storageLocation.Status.ServicePort = ""
 	return nil
 }
 
-func sortPodList(podList []v1.Pod) {
-	l := len(podList)
+// syncCredentials validates the credentials secret and reports the result in status, the plugin pods
+// are still synced when it is invalid, they start as soon as the secret is fixed.
+func (r *StorageLocationsReconciler) syncCredentials(ctx context.Context, storageLocation *boxroomv1.StorageLocations) {
+	ref := storageLocation.GetCredentialsSecretRef()
+	if ref == nil {
+		storageLocation.Status.CredentialsSecret = ""
+		meta.RemoveStatusCondition(&storageLocation.Status.Conditions, boxroomv1.StorageLocationConditionCredentialsReady)
+		return
+	}
 
-	for i := 0; i < l-1; i++ {
-		for j := 0; j < l-i-1; j++ {
-			if podList[j].CreationTimestamp.Time.After(podList[j+1].CreationTimestamp.Time) {
-				podList[j], podList[j+1] = podList[j+1], podList[j]
-			}
-		}
+	condition := metav1.Condition{
+		Type:               boxroomv1.StorageLocationConditionCredentialsReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: storageLocation.Generation,
+		Reason:             "SecretFound",
+		Message:            "credentials secret " + ref.Name + " holds both keys",
+	}
+
+	storageLocation.Status.CredentialsSecret = ref.Name
+	if _, _, err := getCredentials(ctx, r.Client, storageLocation); err != nil {
+		util_log.Logger.Error(err)
+		storageLocation.Status.CredentialsSecret = ""
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "InvalidSecret"
+		condition.Message = err.Error()
 	}
+	meta.SetStatusCondition(&storageLocation.Status.Conditions, condition)
 }
 
-func (r *StorageLocationsReconciler) syncChildService(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
-	childServiceList, err := r.getStorageLocationChildServiceList(ctx, storageLocation)
-	if err != nil {
-		return err
+// syncAvailability validates the storage when the last validation is due or the spec has changed since,
+// and sets the Available and Degraded conditions from the result.
+func (r *StorageLocationsReconciler) syncAvailability(ctx context.Context, storageLocation *boxroomv1.StorageLocations) time.Duration {
+	frequency := r.ValidationFrequency
+	if frequency == 0 {
+		frequency = DefaultStorageLocationValidationFrequency
 	}
 
-	n := 1 - len(childServiceList)
-	createdOrDeletedChildServiceNum := 0
-	for createdOrDeletedChildServiceNum != n {
-		if n > 0 {
-			if err := r.createChildService(ctx, storageLocation); err == nil {
-				createdOrDeletedChildServiceNum++
-			}
+	available := meta.FindStatusCondition(storageLocation.Status.Conditions, boxroomv1.StorageLocationConditionAvailable)
+	if available != nil && available.ObservedGeneration == storageLocation.Generation && storageLocation.Status.LastValidationTime != nil {
+		next := storageLocation.Status.LastValidationTime.Add(frequency)
+		if available.Status != metav1.ConditionTrue {
+			next = storageLocation.Status.LastValidationTime.Add(storageLocationRetryFrequency)
 		}
-
-		if n < 0 {
-			if err := r.deleteChildService(ctx, &childServiceList[0]); err == nil {
-				childServiceList = childServiceList[1:]
-				createdOrDeletedChildServiceNum--
-			}
+		if time.Now().Before(next) {
+			return time.Until(next)
 		}
 	}
 
-	childServiceList, err = r.getStorageLocationChildServiceList(ctx, storageLocation)
+	err := r.validateStorage(ctx, storageLocation)
+	now := metav1.Now()
+	storageLocation.Status.LastValidationTime = &now
+
+	availableCondition := metav1.Condition{
+		Type:               boxroomv1.StorageLocationConditionAvailable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: storageLocation.Generation,
+		Reason:             "ValidationSucceeded",
+		Message:            "the storage is reachable",
+	}
+	degradedCondition := metav1.Condition{
+		Type:               boxroomv1.StorageLocationConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: storageLocation.Generation,
+		Reason:             "ValidationSucceeded",
+		Message:            "the storage is reachable",
+	}
+
+	requeueAfter := frequency
+	storageLocation.Status.LastValidationError = ""
+	switch {
+	case err != nil:
+		util_log.Logger.Errorf("storagelocation %v is unavailable: %v", storageLocation.Name, err)
+		storageLocation.Status.LastValidationError = err.Error()
+		availableCondition.Status = metav1.ConditionFalse
+		availableCondition.Reason = "ValidationFailed"
+		availableCondition.Message = err.Error()
+		degradedCondition.Status = metav1.ConditionTrue
+		degradedCondition.Reason = "ValidationFailed"
+		degradedCondition.Message = err.Error()
+		requeueAfter = storageLocationRetryFrequency
+	case storageLocation.NeedsStoragePlugin() && storageLocation.Status.Replicas < storageLocation.Spec.ContainerSpec.Replicas:
+		degradedCondition.Status = metav1.ConditionTrue
+		degradedCondition.Reason = "ReplicasUnavailable"
+		degradedCondition.Message = fmt.Sprintf("%d of %d storage plugin pods are ready", storageLocation.Status.Replicas, storageLocation.Spec.ContainerSpec.Replicas)
+	}
+
+	meta.SetStatusCondition(&storageLocation.Status.Conditions, availableCondition)
+	meta.SetStatusCondition(&storageLocation.Status.Conditions, degradedCondition)
+	return requeueAfter
+}
+
+// validateStorage runs the health check of the store client, makes sure the bucket exists and lists the top level of it.
+// The filesystem and the s3 clients check the bucket in their health check, only the bucket of a storage plugin is
+// looked up in the bucket list.
+func (r *StorageLocationsReconciler) validateStorage(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
+	storeClient, err := getStoreClient(ctx, r.Client, storageLocation)
 	if err != nil {
 		return err
 	}
+	defer storeClient.Close()
+
+	if err = storeClient.StoragePluginHealthCheck(); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
 
-	if len(childServiceList) != 1 {
-		err = r.syncChildService(ctx, storageLocation)
+	if bucket := storageLocation.GetStoreClientSettings().Bucket; len(bucket) != 0 && storageLocation.NeedsStoragePlugin() {
+		buckets, err := storeClient.ListBucket()
 		if err != nil {
-			return err
+			return fmt.Errorf("can not list buckets: %w", err)
+		}
+		exist := false
+		for _, name := range buckets {
+			exist = exist || name == bucket
+		}
+		if !exist {
+			return fmt.Errorf("bucket %s does not exist", bucket)
 		}
-	} else {
-		storageLocation.Status.Service = childServiceList[0].Name
-		storageLocation.Status.ServiceIp = childServiceList[0].Spec.ClusterIP
-		storageLocation.Status.ServicePort = strconv.Itoa(int(childServiceList[0].Spec.Ports[0].Port))
 	}
 
+	if _, err = storeClient.ListCommonPrefix("", "/"); err != nil {
+		return fmt.Errorf("can not list objects: %w", err)
+	}
 	return nil
 }
 
-func (r *StorageLocationsReconciler) createChildService(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
-	if err := r.Create(ctx, storageLocation.GetService()); err != nil {
-		return err
+func (r *StorageLocationsReconciler) syncChildDeployment(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
+	// the validating webhook may be disabled, a deployment without an image is refused by the api server
+	if len(storageLocation.Spec.ContainerSpec.Image) == 0 {
+		return fmt.Errorf("storagelocation %s has no storage plugin image", storageLocation.Name)
 	}
-	return nil
-}
 
-func (r *StorageLocationsReconciler) deleteChildService(ctx context.Context, service *v1.Service) error {
-	if err := r.Delete(ctx, service); err != nil {
-		return err
+	newDeployment := storageLocation.GetDeployment()
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      newDeployment.Name,
+			Namespace: newDeployment.Namespace,
+		},
 	}
-	return nil
-}
 
-func (r *StorageLocationsReconciler) createChildPod(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
-	if err := r.Create(ctx, storageLocation.GetPod()); err != nil {
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
+		deployment.Labels = newDeployment.Labels
+		deployment.Spec.Replicas = newDeployment.Spec.Replicas
+		if deployment.CreationTimestamp.IsZero() {
+			deployment.Spec.Selector = newDeployment.Spec.Selector
+		}
+		deployment.Spec.Template = newDeployment.Spec.Template
+		return controllerutil.SetControllerReference(storageLocation, deployment, r.Scheme)
+	})
+	if err != nil {
 		return err
 	}
-	return nil
-}
+	util_log.Logger.Infof("storagelocation %v deployment %v: %v", storageLocation.Name, deployment.Name, result)
 
-func (r *StorageLocationsReconciler) deleteChildPod(ctx context.Context, pod *v1.Pod) error {
-	if err := r.Delete(ctx, pod); err != nil {
+	endPoints, err := r.getStorageLocationEndpoints(ctx, storageLocation)
+	if err != nil {
 		return err
 	}
+	storageLocation.Status.Replicas = int(deployment.Status.ReadyReplicas)
+	storageLocation.Status.Pods = endPoints
+
 	return nil
 }
 
-func (r *StorageLocationsReconciler) updateStorageLocation(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
-	newStorageLocation, err := r.getStorageLocation(ctx, ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Namespace: storageLocation.Namespace,
-			Name:      storageLocation.Name,
+// syncChildService creates the service of the storage plugin or updates its ports in place, the cluster ip is kept.
+func (r *StorageLocationsReconciler) syncChildService(ctx context.Context, storageLocation *boxroomv1.StorageLocations) error {
+	newService := storageLocation.GetService()
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      newService.Name,
+			Namespace: newService.Namespace,
 		},
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		service.Labels = newService.Labels
+		service.Spec.Selector = newService.Spec.Selector
+		service.Spec.Ports = newService.Spec.Ports
+		return controllerutil.SetControllerReference(storageLocation, service, r.Scheme)
 	})
 	if err != nil {
 		return err
 	}
+	util_log.Logger.Infof("storagelocation %v service %v: %v", storageLocation.Name, service.Name, result)
+
+	storageLocation.Status.Service = service.Name
+	storageLocation.Status.ServiceIp = service.Spec.ClusterIP
+	storageLocation.Status.ServicePort = strconv.Itoa(int(service.Spec.Ports[0].Port))
+
+	return nil
+}
 
-	if reflect.DeepEqual(newStorageLocation.Status, storageLocation.Status) {
+// updateStorageLocation patches the status of the storage location against the status it was read with,
+// on a conflict the status is applied on top of the latest version of the object again.
+func (r *StorageLocationsReconciler) updateStorageLocation(ctx context.Context, storageLocation, original *boxroomv1.StorageLocations) error {
+	storageLocation.Status.ObservedGeneration = storageLocation.Generation
+	if reflect.DeepEqual(original.Status, storageLocation.Status) {
 		util_log.Logger.Infof("storagelocation %v update status: storagelocation status is same as old", storageLocation.Name)
 		return nil
 	}
 
-	if newStorageLocation.ObjectMeta.Generation > storageLocation.ObjectMeta.Generation {
-		util_log.Logger.Infof("storagelocation %v update status: new version's generation is greater than old version's", storageLocation.Name)
-		return nil
-	}
+	status := storageLocation.Status.DeepCopy()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := r.Status().Patch(ctx, storageLocation, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+		if !errors.IsConflict(err) {
+			return err
+		}
 
-	if err = r.Update(ctx, storageLocation); err != nil {
+		latest := &boxroomv1.StorageLocations{}
+		if getErr := r.Get(ctx, client.ObjectKeyFromObject(storageLocation), latest); getErr != nil {
+			return getErr
+		}
+		original = latest.DeepCopy()
+		latest.Status = *status.DeepCopy()
+		*storageLocation = *latest
+		return err
+	})
+	if err != nil {
 		return err
 	}
-	util_log.Logger.Infof("storagelocation %v update status: successful update status, old is %v, new is %v", storageLocation.Name, newStorageLocation.Status, storageLocation.Status)
+	util_log.Logger.Infof("storagelocation %v update status: successful update status, old is %v, new is %v", storageLocation.Name, original.Status, storageLocation.Status)
 	return nil
 }
 
-func (r *StorageLocationsReconciler) getStorageLocationChildPodList(ctx context.Context, storageLocation *boxroomv1.StorageLocations) ([]v1.Pod, error) {
+// getStorageLocationEndpoints lists the ips of the ready storage plugin pods.
+func (r *StorageLocationsReconciler) getStorageLocationEndpoints(ctx context.Context, storageLocation *boxroomv1.StorageLocations) ([]string, error) {
 	podList := &v1.PodList{}
-	if err := r.List(ctx, podList, client.InNamespace(storageLocation.Namespace)); err != nil {
+	if err := r.List(ctx, podList, client.InNamespace(storageLocation.Namespace), client.MatchingLabels(storageLocation.GetWorkloadLabels())); err != nil {
 		util_log.Logger.Error(err)
 		return nil, err
 	}
 
-	var childPodList []v1.Pod
+	var endPoints []string
 	for _, pod := range podList.Items {
-		if _, alive := deletedPodCache.Get(pod.Name); !alive && pod.Labels["workload-kind"] == "storagelocations" && pod.OwnerReferences[0].Name == storageLocation.Name && pod.OwnerReferences[0].UID == storageLocation.UID && pod.DeletionTimestamp == nil {
-			childPodList = append(childPodList, pod)
+		if pod.DeletionTimestamp == nil && len(pod.Status.PodIP) != 0 && isPodReady(&pod) {
+			endPoints = append(endPoints, pod.Status.PodIP)
 		}
 	}
-
-	sortPodList(childPodList)
-	return childPodList, nil
+	sort.Strings(endPoints)
+	return endPoints, nil
 }
 
-func (r *StorageLocationsReconciler) getStorageLocationChildServiceList(ctx context.Context, storageLocation *boxroomv1.StorageLocations) ([]v1.Service, error) {
-	serviceList := &v1.ServiceList{}
-	if err := r.List(ctx, serviceList, client.InNamespace(storageLocation.Namespace)); err != nil {
-		util_log.Logger.Error(err)
-		return nil, err
-	}
-
-	var childServiceList []v1.Service
-	for _, service := range serviceList.Items {
-		if service.Labels["workload-kind"] == "storagelocations" && service.OwnerReferences[0].Name == storageLocation.Name && service.OwnerReferences[0].UID == storageLocation.UID && service.DeletionTimestamp == nil {
-			childServiceList = append(childServiceList, service)
+func isPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
 		}
 	}
-
-	return childServiceList, nil
+	return false
 }
 
 type EnqueueRequestForStorageLocationChildren struct {
@@ -349,19 +562,36 @@ func (e *EnqueueRequestForStorageLocationChildren) Generic(ctx context.Context,
 	q.Add(reconcile.Request{NamespacedName: *e.getStorageLocationRequestFromObject(evt.Object)})
 }
 
+// getStorageLocationRequestFromObject finds the storage location by the workload labels, the plugin pods
+// are owned by the replica sets of the deployment so their owner references do not lead to it.
 func (e *EnqueueRequestForStorageLocationChildren) getStorageLocationRequestFromObject(object client.Object) *types.NamespacedName {
-	if len(object.GetOwnerReferences()) == 0 {
-		return nil
-	}
-	storagelocation := object.GetOwnerReferences()[0]
 	return &types.NamespacedName{
-		Name:      storagelocation.Name,
+		Name:      object.GetLabels()["workload-name"],
 		Namespace: object.GetNamespace(),
 	}
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// getStorageLocationRequestsForSecret enqueues the storage locations which reference the secret as their credentials.
+func (r *StorageLocationsReconciler) getStorageLocationRequestsForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	storageLocationList := &boxroomv1.StorageLocationsList{}
+	if err := r.List(ctx, storageLocationList, client.InNamespace(secret.GetNamespace())); err != nil {
+		util_log.Logger.Error(err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, storageLocation := range storageLocationList.Items {
+		if ref := storageLocation.GetCredentialsSecretRef(); ref != nil && ref.Name == secret.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: storageLocation.Namespace, Name: storageLocation.Name}})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. The secrets are only watched by their metadata,
+// so the cache does not hold the content of every secret in the cluster.
 func (r *StorageLocationsReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&boxroomv1.StorageLocations{}).Watches(&v1.Pod{}, &EnqueueRequestForStorageLocationChildren{}).Watches(&v1.Service{}, &EnqueueRequestForStorageLocationChildren{}).Complete(r)
+		For(&boxroomv1.StorageLocations{}).Owns(&appsv1.Deployment{}).Watches(&v1.Pod{}, &EnqueueRequestForStorageLocationChildren{}).Owns(&v1.Service{}).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.getStorageLocationRequestsForSecret), builder.OnlyMetadata).Complete(r)
 }
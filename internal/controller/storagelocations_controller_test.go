@@ -0,0 +1,129 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	boxroomv1 "github.io/misskaori/boxroom-crd/api/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"testing"
+	"time"
+)
+
+// newDeletedStorageLocation builds a storage location which is deleted while its finalizer is still set.
+func newDeletedStorageLocation(policy boxroomv1.StorageLocationDeletionPolicy) *boxroomv1.StorageLocations {
+	return &boxroomv1.StorageLocations{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              "minio",
+			DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			Finalizers:        []string{boxroomv1.StorageLocationFinalizer},
+		},
+		Spec: boxroomv1.StorageLocationsSpec{DeletionPolicy: policy},
+	}
+}
+
+// newStorageLocationBackups builds a backup kept in the storage location minio and a backup replicated to it.
+func newStorageLocationBackups() (*boxroomv1.Backups, *boxroomv1.Backups) {
+	primary := &boxroomv1.Backups{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "primary"},
+		Spec:       boxroomv1.BackupsSpec{StorageLocation: "minio"},
+		Status:     boxroomv1.BackupsStatus{Phase: boxroomv1.BackupPhaseCompleted},
+	}
+	replicated := &boxroomv1.Backups{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "replicated"},
+		Spec:       boxroomv1.BackupsSpec{StorageLocation: "s3", ReplicaStorageLocations: []string{"minio"}},
+		Status: boxroomv1.BackupsStatus{
+			Phase: boxroomv1.BackupPhaseCompleted,
+			StorageLocations: []boxroomv1.BackupStorageLocationStatus{{
+				StorageLocationReference: boxroomv1.StorageLocationReference{Namespace: "default", Name: "minio"},
+				Phase:                    boxroomv1.BackupPhaseCompleted,
+			}},
+		},
+	}
+	return primary, replicated
+}
+
+func TestStorageLocationDeletionBlockedByBackups(t *testing.T) {
+	primary, replicated := newStorageLocationBackups()
+	c := newFakeClientBuilder(t).WithObjects(newDeletedStorageLocation(""), primary, replicated).Build()
+	r := &StorageLocationsReconciler{Client: c, Scheme: c.Scheme()}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "minio"}}
+	result, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter <= 0 {
+		t.Errorf("blocked deletion is not checked again")
+	}
+
+	storageLocation := &boxroomv1.StorageLocations{}
+	if err = c.Get(context.Background(), req.NamespacedName, storageLocation); err != nil {
+		t.Fatalf("storage location is gone while backups refer to it: %v", err)
+	}
+	blocked := meta.FindStatusCondition(storageLocation.Status.Conditions, boxroomv1.StorageLocationConditionDeletionBlocked)
+	if blocked == nil || blocked.Status != metav1.ConditionTrue || blocked.Message != "backups refer to the storagelocation: primary, replicated" {
+		t.Errorf("deletion blocked condition is %+v, want it to name both backups", blocked)
+	}
+
+	// the finalizer is removed once the backups are gone
+	for _, backup := range []*boxroomv1.Backups{primary, replicated} {
+		if err = c.Delete(context.Background(), backup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = r.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Get(context.Background(), req.NamespacedName, storageLocation); !errors.IsNotFound(err) {
+		t.Errorf("storage location is kept without backups: %v", err)
+	}
+}
+
+func TestStorageLocationDeletionOrphansBackups(t *testing.T) {
+	primary, replicated := newStorageLocationBackups()
+	c := newFakeClientBuilder(t).WithObjects(newDeletedStorageLocation(boxroomv1.StorageLocationDeletionPolicyOrphan), primary, replicated).Build()
+	r := &StorageLocationsReconciler{Client: c, Scheme: c.Scheme()}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "minio"}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &boxroomv1.StorageLocations{}); !errors.IsNotFound(err) {
+		t.Errorf("storage location is kept although it orphans its backups: %v", err)
+	}
+
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "primary"}, primary); err != nil {
+		t.Fatal(err)
+	}
+	if !primary.IsOrphaned() {
+		t.Errorf("backup primary is not orphaned, conditions are %+v", primary.Status.Conditions)
+	}
+
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "replicated"}, replicated); err != nil {
+		t.Fatal(err)
+	}
+	replica := replicated.GetStorageLocationStatus(boxroomv1.StorageLocationReference{Namespace: "default", Name: "minio"})
+	if replicated.IsOrphaned() || replica == nil || replica.Phase != boxroomv1.BackupPhaseFailed {
+		t.Errorf("backup replicated has replica status %+v, want the replica failed and the backup kept", replica)
+	}
+}
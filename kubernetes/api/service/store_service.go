@@ -6,22 +6,22 @@ import (
 	utillog "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
 )
 
-func BackupService(agentController *controller.AgentController, root *tree.KubernetesRoot, filters map[string]tree.Filter) error {
-	err := agentController.Backup(root, filters)
+func BackupService(agentController *controller.AgentController, root *tree.KubernetesRoot, filters map[string]tree.Filter) (tree.Status, error) {
+	status, err := agentController.Backup(root, filters)
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		return status, err
 	}
 
-	return nil
+	return status, nil
 }
 
-func RestoreService(agentController *controller.AgentController, root *tree.KubernetesRoot, filters map[string]tree.Filter) error {
-	err := agentController.Restore(root, filters)
+func RestoreService(agentController *controller.AgentController, root *tree.KubernetesRoot, filters map[string]tree.Filter) (tree.Status, error) {
+	status, err := agentController.Restore(root, filters)
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		return status, err
 	}
 
-	return nil
+	return status, nil
 }
@@ -2,6 +2,10 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	mapset "github.com/deckarep/golang-set"
+	k8sagent "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-agent"
 	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
 	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
 	"github.io/misskaori/boxroom-crd/kubernetes/storage/dir"
@@ -12,17 +16,23 @@ import (
 	utillog "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
 )
 
+// ErrRestoreLoggerNotUploaded is wrapped by the error of Restore when the restore log could not be uploaded.
+var ErrRestoreLoggerNotUploaded = errors.New("the restore log is not uploaded")
+
 type AgentController struct {
 	KubernetesAgent tree.Agent
 	StorageClient   storeclient.StoreClient
 	DirDefinition   dir.StorageDirDefinition
+	// Replicas are the further storages which a backup is uploaded to, the backup log is only kept in StorageClient.
+	// The result of every replica is recorded on it once the backup has been uploaded to StorageClient.
+	Replicas []*storeagent.StoreTarget
 }
 
-func (controller *AgentController) Backup(root *tree.KubernetesRoot, filters map[string]tree.Filter) error {
-	coreStorageAgent, assistStorageAgent, err := getStorageAgent(controller.StorageClient, controller.DirDefinition)
+func (controller *AgentController) Backup(root *tree.KubernetesRoot, filters map[string]tree.Filter) (tree.Status, error) {
+	coreStorageAgent, assistStorageAgent, err := getStorageAgent(controller.StorageClient, controller.DirDefinition, controller.Replicas, nil)
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		return nil, err
 	}
 
 	err = assistStorageAgent.InitLoggerAgent(root)
@@ -43,42 +53,56 @@ func (controller *AgentController) Backup(root *tree.KubernetesRoot, filters map
 
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		return nil, err
 	}
 
+	missionStatus := assistStorageAgent.StatusLogger
+
 	ctx := context.WithValue(context.Background(), globleimmobile.FileLogger, fileLogger)
-	ctx = context.WithValue(ctx, globleimmobile.MissionStatus, assistStorageAgent.StatusLogger)
+	ctx = context.WithValue(ctx, globleimmobile.MissionStatus, missionStatus)
 
 	fileLogger.Info("begin to backup")
 
 	root, err = controller.KubernetesAgent.GetResourceTree(root, filters, ctx)
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		missionStatus.SetStatus(k8sagent.StatusFailed)
+		return missionStatus, err
 	}
 
 	err = coreStorageAgent.ApplyResourceTree(root, ctx)
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		missionStatus.SetStatus(k8sagent.StatusFailed)
+		return missionStatus, err
 	}
+	missionStatus.AddSucceededObjects(root.CountObjects())
 
 	fileLogger.Info("backup is completed")
 
 	err = assistStorageAgent.UploadLocalLogger(root)
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		return missionStatus, err
 	}
 
-	return nil
+	return missionStatus, nil
 }
 
-func (controller *AgentController) Restore(root *tree.KubernetesRoot, filters map[string]tree.Filter) error {
-	coreStorageAgent, assistStorageAgent, err := getStorageAgent(controller.StorageClient, controller.DirDefinition)
+// Restore restores the backup tree named by root, the restore log is uploaded under the restore tree of root
+// whether or not the restore succeeds once the logger has been initialised. root.TreeKind is TreeRestoreKind then,
+// and the returned error wraps ErrRestoreLoggerNotUploaded when the log could not be uploaded.
+func (controller *AgentController) Restore(root *tree.KubernetesRoot, filters map[string]tree.Filter) (tree.Status, error) {
+	coreResources, err := getCoreResources(controller.KubernetesAgent)
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		return nil, err
+	}
+
+	coreStorageAgent, assistStorageAgent, err := getStorageAgent(controller.StorageClient, controller.DirDefinition, nil, coreResources)
+	if err != nil {
+		utillog.Logger.Error(err)
+		return nil, err
 	}
 
 	err = assistStorageAgent.InitLoggerAgent(root)
@@ -99,36 +123,98 @@ func (controller *AgentController) Restore(root *tree.KubernetesRoot, filters ma
 
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		return nil, err
 	}
 
+	missionStatus := assistStorageAgent.StatusLogger
+
 	ctx := context.WithValue(context.Background(), globleimmobile.FileLogger, fileLogger)
-	ctx = context.WithValue(ctx, globleimmobile.MissionStatus, assistStorageAgent.StatusLogger)
+	ctx = context.WithValue(ctx, globleimmobile.MissionStatus, missionStatus)
 
 	fileLogger.Info("begin to restore")
 
-	root, err = coreStorageAgent.GetResourceTree(root, filters, ctx)
+	// the resource tree is read into root itself, root is kept to name the restore log on failure
+	_, err = coreStorageAgent.GetResourceTree(root, filters, ctx)
 	if err != nil {
 		fileLogger.Error(err)
-		return err
+		missionStatus.SetStatus(k8sagent.StatusFailed)
+		return missionStatus, errors.Join(err, controller.uploadRestoreLogger(root, assistStorageAgent))
 	}
 
 	err = controller.KubernetesAgent.ApplyResourceTree(root, ctx)
 	if err != nil {
 		fileLogger.Error(err)
-		return err
+		missionStatus.SetStatus(k8sagent.StatusFailed)
+		return missionStatus, errors.Join(err, controller.uploadRestoreLogger(root, assistStorageAgent))
 	}
 
 	root.TreeKind = immobile.TreeRestoreKind
 	err = coreStorageAgent.ApplyResourceTree(root, ctx)
 	if err != nil {
 		utillog.Logger.Error(err)
-		return err
+		missionStatus.SetStatus(k8sagent.StatusFailed)
+		return missionStatus, errors.Join(err, controller.uploadRestoreLogger(root, assistStorageAgent))
 	}
 
 	fileLogger.Info("restore is completed")
 
-	err = assistStorageAgent.UploadLocalLogger(root)
+	err = controller.uploadRestoreLogger(root, assistStorageAgent)
+	if err != nil {
+		return missionStatus, err
+	}
+
+	return missionStatus, nil
+}
+
+// uploadRestoreLogger uploads the restore log under the restore tree of root, root is named after
+// the restore tree first when the restore has failed before its tree is stored.
+func (controller *AgentController) uploadRestoreLogger(root *tree.KubernetesRoot, assistStorageAgent *storeagent.AssistLogStoreAgent) error {
+	if root.TreeKind != immobile.TreeRestoreKind {
+		root.TreeKind = immobile.TreeRestoreKind
+		controller.DirDefinition.PreHandleRestoreRoot(root)
+	}
+
+	err := assistStorageAgent.UploadLocalLogger(root)
+	if err != nil {
+		utillog.Logger.Error(err)
+		return fmt.Errorf("%w: %v", ErrRestoreLoggerNotUploaded, err)
+	}
+	return nil
+}
+
+// Close closes the store clients of the controller and of its replicas.
+func (controller *AgentController) Close() error {
+	err := controller.StorageClient.Close()
+	for _, replica := range controller.Replicas {
+		if closeErr := replica.Client.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (controller *AgentController) CheckRemoteTreeExist(root *tree.KubernetesRoot) (bool, error) {
+	coreStorageAgent := &storeagent.CoreStoreAgent{
+		Client:        controller.StorageClient,
+		DirDefinition: controller.DirDefinition,
+	}
+
+	exist, err := coreStorageAgent.CheckRemoteStorageExist(root)
+	if err != nil {
+		utillog.Logger.Error(err)
+		return false, err
+	}
+
+	return exist, nil
+}
+
+func (controller *AgentController) DeleteRemoteTree(root *tree.KubernetesRoot) error {
+	coreStorageAgent := &storeagent.CoreStoreAgent{
+		Client:        controller.StorageClient,
+		DirDefinition: controller.DirDefinition,
+	}
+
+	err := coreStorageAgent.DeleteRemoteStorage(root)
 	if err != nil {
 		utillog.Logger.Error(err)
 		return err
@@ -137,10 +223,43 @@ func (controller *AgentController) Restore(root *tree.KubernetesRoot, filters ma
 	return nil
 }
 
-func getStorageAgent(storageClient storeclient.StoreClient, dirDefinition dir.StorageDirDefinition) (tree.Agent, *storeagent.AssistLogStoreAgent, error) {
+// CopyRemoteTree replicates an existing remote tree from the storage of the controller to the target storage.
+func (controller *AgentController) CopyRemoteTree(root *tree.KubernetesRoot, target storeclient.StoreClient) error {
+	coreStorageAgent := &storeagent.CoreStoreAgent{
+		Client:        controller.StorageClient,
+		DirDefinition: controller.DirDefinition,
+	}
+
+	err := coreStorageAgent.CopyRemoteStorage(root, target)
+	if err != nil {
+		utillog.Logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// coreResourcesGetter is implemented by the kubernetes agents which can discover the core resources of the cluster.
+type coreResourcesGetter interface {
+	GetCoreResources() (mapset.Set, error)
+}
+
+// getCoreResources discovers the core resources of the cluster which is restored, so a restore resolves the bare
+// resource names of its filters the same way as a backup of that cluster.
+func getCoreResources(kubernetesAgent tree.Agent) (mapset.Set, error) {
+	getter, ok := kubernetesAgent.(coreResourcesGetter)
+	if !ok {
+		return nil, nil
+	}
+	return getter.GetCoreResources()
+}
+
+func getStorageAgent(storageClient storeclient.StoreClient, dirDefinition dir.StorageDirDefinition, replicas []*storeagent.StoreTarget, coreResources mapset.Set) (tree.Agent, *storeagent.AssistLogStoreAgent, error) {
 	coreStorageAgent, err := (&storeagent.StorageConfig{
 		Client:        storageClient,
 		DirDefinition: dirDefinition,
+		Replicas:      replicas,
+		CoreResources: coreResources,
 	}).AgentInit()
 
 	if err != nil {
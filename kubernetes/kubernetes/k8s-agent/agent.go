@@ -22,7 +22,6 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
-	"strings"
 )
 
 type KubernetesAgent struct {
@@ -30,6 +29,8 @@ type KubernetesAgent struct {
 	DynamicClient   *dynamic.DynamicClient
 	DiscoveryClient *discovery.DiscoveryClient
 	ClientSet       *kubernetes.Clientset
+	// DefaultFilters are the exclusions which every backup applies, the built-in ones are used if it is nil.
+	DefaultFilters *k8sfilter.DefaultFilters
 }
 
 func (client *KubernetesAgent) GetResourceTree(root *tree.KubernetesRoot, filters map[string]tree.Filter, ctx context.Context) (*tree.KubernetesRoot, error) {
@@ -56,15 +57,18 @@ func (client *KubernetesAgent) GetResourceTree(root *tree.KubernetesRoot, filter
 	}
 
 	fileLogger.Infof("filt tree: begin to filt tree, there are %d tree filters", len(filters))
-	preHandleNamespaceFilter(filters)
-	preHandleResourceFilter(filters)
+	defaultFilters := client.getDefaultFilters(filters)
+	preHandleNamespaceFilter(filters, defaultFilters)
+	preHandleResourceFilter(filters, defaultFilters)
 	clusterInclude := false
+	selection := &objectSelection{defaultObjectFilter: defaultFilters.ObjectFilter()}
+	coreResources := getCoreResources(vs)
 
 	for key, f := range filters {
 		fileLogger.Infof("filt resouce: The kind of this s3-filter is %s ", f.GetFilterKind())
 		switch key {
 		case immobile.ResourceKind:
-			filtrateResource(vs, f, ctx)
+			vs = filtrateResource(vs, f, coreResources, ctx)
 			delete(filters, immobile.ResourceKind)
 		case immobile.NamespaceKind:
 			filtrateNamespace(ns, f, ctx)
@@ -73,10 +77,38 @@ func (client *KubernetesAgent) GetResourceTree(root *tree.KubernetesRoot, filter
 			if f.GetFilterKind() == immobile.ClusterKind && f.GetFilterPattern() {
 				clusterInclude = true
 			}
+		case immobile.LabelSelectorKind:
+			if labelFilter, ok := f.(*k8sfilter.KubernetesLabelFilter); ok {
+				selection.labelSelector = labelFilter.Selector.String()
+			}
+		case immobile.ObjectFilterKind:
+			if objectFilter, ok := f.(*k8sfilter.KubernetesObjectFilter); ok {
+				selection.objectFilter = objectFilter
+			}
 		}
 	}
 	fileLogger.Infof("begin to build resouece tree")
-	return client.buildGroupAndVersionTree(root, vs, ns, clusterInclude, ctx)
+	if !clusterInclude {
+		vs = filtrateClusterResource(vs, defaultFilters.ClusterResourceSet(), coreResources, ctx)
+	}
+	return client.buildGroupAndVersionTree(root, vs, ns, selection, ctx)
+}
+
+// getDefaultFilters gets the default exclusions of the agent, overridden by the default filters of the backup if
+// it has any. The override is taken out of the filters as it is applied through the other filters.
+func (client *KubernetesAgent) getDefaultFilters(filters map[string]tree.Filter) *k8sfilter.DefaultFilters {
+	defaultFilters := client.DefaultFilters
+	if defaultFilters == nil {
+		defaultFilters = k8sfilter.BuiltinDefaultFilters()
+	}
+
+	if filter, ok := filters[immobile.DefaultFiltersKind]; ok {
+		delete(filters, immobile.DefaultFiltersKind)
+		if override, ok := filter.(*k8sfilter.DefaultFilters); ok {
+			return defaultFilters.Merge(override)
+		}
+	}
+	return defaultFilters
 }
 
 func (client *KubernetesAgent) ApplyResourceTree(root *tree.KubernetesRoot, ctx context.Context) error {
@@ -262,6 +294,7 @@ func (client *KubernetesAgent) applyObject(object *tree.Object, ctx context.Cont
 			missionStatus.AddFailedObjects(objectPathName, err)
 			return err
 		}
+		missionStatus.AddSucceededObjects(1)
 	} else {
 		_, err := client.DynamicClient.Resource(*object.GVR).Namespace(object.Metadata.Namespace).Create(context.TODO(), object.Definition, metav1.CreateOptions{})
 		statusErr, _ := err.(k8serrors.APIStatus)
@@ -274,13 +307,14 @@ func (client *KubernetesAgent) applyObject(object *tree.Object, ctx context.Cont
 			fileLogger.Info(err)
 		} else {
 			fileLogger.Infof("restore success: namespace: %s resource: %s object: %s", object.Metadata.Namespace, object.Metadata.Resource, object.Metadata.Name)
+			missionStatus.AddSucceededObjects(1)
 		}
 	}
 
 	return nil
 }
 
-func (client *KubernetesAgent) buildGroupAndVersionTree(root *tree.KubernetesRoot, groupAndVersions []*metav1.APIResourceList, namespaces *v1.NamespaceList, clusterInclude bool, ctx context.Context) (*tree.KubernetesRoot, error) {
+func (client *KubernetesAgent) buildGroupAndVersionTree(root *tree.KubernetesRoot, groupAndVersions []*metav1.APIResourceList, namespaces *v1.NamespaceList, selection *objectSelection, ctx context.Context) (*tree.KubernetesRoot, error) {
 	fileLogger, _ := ctx.Value(globle_immobile.FileLogger).(*logrus.Logger)
 
 	for _, groupAndVersion := range groupAndVersions {
@@ -290,7 +324,7 @@ func (client *KubernetesAgent) buildGroupAndVersionTree(root *tree.KubernetesRoo
 			return nil, err
 		}
 		v := root.AddChildren(gv.Group).AddChildren(gv.Version)
-		err = client.buildResourceTree(v, &gv, groupAndVersion, namespaces, clusterInclude, ctx)
+		err = client.buildResourceTree(v, &gv, groupAndVersion, namespaces, selection, ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -308,13 +342,10 @@ func (client *KubernetesAgent) buildGroupAndVersionTree(root *tree.KubernetesRoo
 	return root, nil
 }
 
-func (client *KubernetesAgent) buildResourceTree(version *tree.Version, gv *schema.GroupVersion, groupAndVersion *metav1.APIResourceList, namespaces *v1.NamespaceList, clusterInclude bool, ctx context.Context) error {
+func (client *KubernetesAgent) buildResourceTree(version *tree.Version, gv *schema.GroupVersion, groupAndVersion *metav1.APIResourceList, namespaces *v1.NamespaceList, selection *objectSelection, ctx context.Context) error {
 	fileLogger, _ := ctx.Value(globle_immobile.FileLogger).(*logrus.Logger)
 
 	for _, api := range groupAndVersion.APIResources {
-		if !clusterInclude && !api.Namespaced && !defaultIncludeClusterResource().Contains(api.Name) {
-			continue
-		}
 		r := version.AddChildren(api.Name, !api.Namespaced)
 		gvr := schema.GroupVersionResource{
 			Group:    gv.Group,
@@ -322,7 +353,7 @@ func (client *KubernetesAgent) buildResourceTree(version *tree.Version, gv *sche
 			Resource: api.Name,
 		}
 		fileLogger.Infof("build resource branches: group:%s version:%s resource:%s", gvr.Group, gvr.Version, gvr.Resource)
-		err := client.buildNamespaceTree(r, &gvr, namespaces, ctx)
+		err := client.buildNamespaceTree(r, &gvr, namespaces, selection, ctx)
 		if err != nil {
 			fileLogger.Error(err)
 			return err
@@ -337,7 +368,7 @@ func (client *KubernetesAgent) buildResourceTree(version *tree.Version, gv *sche
 	return nil
 }
 
-func (client *KubernetesAgent) buildNamespaceTree(resource *tree.Resource, gvr *schema.GroupVersionResource, namespaces *v1.NamespaceList, ctx context.Context) error {
+func (client *KubernetesAgent) buildNamespaceTree(resource *tree.Resource, gvr *schema.GroupVersionResource, namespaces *v1.NamespaceList, selection *objectSelection, ctx context.Context) error {
 	if resource.IsCluster {
 		resource.AddChildren(immobile.ClusterLevelNamespace)
 	} else {
@@ -345,7 +376,7 @@ func (client *KubernetesAgent) buildNamespaceTree(resource *tree.Resource, gvr *
 			resource.AddChildren(namespace.Name)
 		}
 	}
-	_ = client.buildObjectTree(resource, gvr, ctx)
+	_ = client.buildObjectTree(resource, gvr, selection, ctx)
 	for _, namespace := range resource.Namespaces {
 		if len(namespace.Objects) == 0 && !resource.DeleteChildren(namespace) {
 			e := fmt.Sprintf("there is no such namespace to delete: %v", namespace)
@@ -355,24 +386,20 @@ func (client *KubernetesAgent) buildNamespaceTree(resource *tree.Resource, gvr *
 	return nil
 }
 
-func (client *KubernetesAgent) buildObjectTree(resource *tree.Resource, gvr *schema.GroupVersionResource, ctx context.Context) error {
+// buildObjectTree lists the objects of the resource, the label selector of the selection is handed to the api server
+// so only the matching objects are listed, the other filters of the selection are applied to the listed objects.
+func (client *KubernetesAgent) buildObjectTree(resource *tree.Resource, gvr *schema.GroupVersionResource, selection *objectSelection, ctx context.Context) error {
 	fileLogger, _ := ctx.Value(globle_immobile.FileLogger).(*logrus.Logger)
 
-	unstructObj, err := client.DynamicClient.Resource(*gvr).List(context.TODO(), metav1.ListOptions{})
-	filtFlag := false
-	objectFilter := defaultObjectFilter()
-	if _, ok := objectFilter[gvr.Resource]; ok {
-		filtFlag = true
-	}
-
+	unstructObj, err := client.DynamicClient.Resource(*gvr).List(context.TODO(), metav1.ListOptions{LabelSelector: selection.labelSelector})
 	if err != nil {
 		//TODO:HANDLE THIS ERROR MORE GRACEFULLY
 		fileLogger.Infof("could not found the requested object: group:%s, version:%s, resource:%s native error info:%s", gvr.Group, gvr.Version, gvr.Resource, err.Error())
 		return err
 	}
 	for idx, object := range unstructObj.Items {
-
-		if filtFlag && !preHandleObjectFilter(&object, gvr) {
+		if !selection.selects(&object, gvr) {
+			fileLogger.Infof("filt resouce: kind:object resource:%s namespace:%s name:%s handle:excluded", gvr.Resource, object.GetNamespace(), object.GetName())
 			continue
 		}
 
@@ -402,12 +429,29 @@ func (client *KubernetesAgent) buildObjectTree(resource *tree.Resource, gvr *sch
 	return nil
 }
 
+// objectSelection narrows down the objects which are listed for every resource of the tree.
+type objectSelection struct {
+	labelSelector       string
+	objectFilter        *k8sfilter.KubernetesObjectFilter
+	defaultObjectFilter *k8sfilter.KubernetesObjectFilter
+}
+
+// selects honors the opt-out annotation of the object, the default excluded objects and the object filter of its resource.
+func (selection *objectSelection) selects(object *unstructured.Unstructured, gvr *schema.GroupVersionResource) bool {
+	if k8sfilter.IsExcludedFromBackup(object) {
+		return false
+	}
+	if selection.defaultObjectFilter != nil && !selection.defaultObjectFilter.Selects(gvr.Group, gvr.Resource, object.GetName()) {
+		return false
+	}
+	return selection.objectFilter == nil || selection.objectFilter.Selects(gvr.Group, gvr.Resource, object.GetName())
+}
+
 func filtrateNamespace(namespaces *v1.NamespaceList, filter tree.Filter, ctx context.Context) {
 	fileLogger, _ := ctx.Value(globle_immobile.FileLogger).(*logrus.Logger)
 
 	for i := 0; i < len(namespaces.Items); {
-		addflag := (filter.GetFilterPattern() && filter.GetFilterSet().Contains(namespaces.Items[i].Name)) || (!filter.GetFilterPattern() && !filter.GetFilterSet().Contains(namespaces.Items[i].Name))
-		if !addflag {
+		if !k8sfilter.Selects(filter, namespaces.Items[i].Name) {
 			fileLogger.Infof("filt resouce: kind:namespace name:%s handle:excluded", namespaces.Items[i].Name)
 			namespaces.Items = append(namespaces.Items[:i], namespaces.Items[i+1:]...)
 		} else {
@@ -417,18 +461,68 @@ func filtrateNamespace(namespaces *v1.NamespaceList, filter tree.Filter, ctx con
 	}
 }
 
-func filtrateResource(groupAndVersions []*metav1.APIResourceList, filter tree.Filter, ctx context.Context) {
+// GetCoreResources discovers the names of the resources of the core group of the cluster, the storage agent
+// resolves the bare resource names of a restore against them the same way the backup has resolved them.
+func (client *KubernetesAgent) GetCoreResources() (mapset.Set, error) {
+	coreVersion, err := client.DiscoveryClient.ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		return nil, err
+	}
+	return getCoreResources([]*metav1.APIResourceList{coreVersion}), nil
+}
+
+// getCoreResources gets the names of the resources of the core group, which the bare resource names of the
+// resource filters are resolved to first.
+func getCoreResources(groupAndVersions []*metav1.APIResourceList) mapset.Set {
+	coreResources := mapset.NewSet()
+	for _, version := range groupAndVersions {
+		gv, err := schema.ParseGroupVersion(version.GroupVersion)
+		if err != nil || len(gv.Group) != 0 {
+			continue
+		}
+		for _, api := range version.APIResources {
+			coreResources.Add(api.Name)
+		}
+	}
+	return coreResources
+}
+
+// filtrateResource filters the resources by their name, resource.group or resource.version.group.
+func filtrateResource(groupAndVersions []*metav1.APIResourceList, filter tree.Filter, coreResources mapset.Set, ctx context.Context) []*metav1.APIResourceList {
+	fileLogger, _ := ctx.Value(globle_immobile.FileLogger).(*logrus.Logger)
+
+	return filtrateAPIResources(groupAndVersions, func(gvr schema.GroupVersionResource, api *metav1.APIResource) bool {
+		if !k8sfilter.SelectsResource(filter, gvr, coreResources) {
+			fileLogger.Infof("filt resouce: kind:tree name:%s handle:excluded", gvr.GroupResource().String())
+			return false
+		}
+		fileLogger.Infof("filt resouce: kind:tree name:%s handle:included", gvr.GroupResource().String())
+		return true
+	})
+}
+
+// filtrateClusterResource drops the cluster resources but the ones which are backed up by default.
+func filtrateClusterResource(groupAndVersions []*metav1.APIResourceList, clusterResources, coreResources mapset.Set, ctx context.Context) []*metav1.APIResourceList {
 	fileLogger, _ := ctx.Value(globle_immobile.FileLogger).(*logrus.Logger)
 
+	return filtrateAPIResources(groupAndVersions, func(gvr schema.GroupVersionResource, api *metav1.APIResource) bool {
+		if api.Namespaced || k8sfilter.MatchAnyResource(clusterResources, gvr, coreResources) {
+			return true
+		}
+		fileLogger.Infof("filt resouce: kind:cluster name:%s handle:excluded", gvr.GroupResource().String())
+		return false
+	})
+}
+
+// filtrateAPIResources drops the resources which are not kept, and the group versions which are left empty.
+func filtrateAPIResources(groupAndVersions []*metav1.APIResourceList, keep func(gvr schema.GroupVersionResource, api *metav1.APIResource) bool) []*metav1.APIResourceList {
 	for j := 0; j < len(groupAndVersions); {
 		version := groupAndVersions[j]
+		gv, _ := schema.ParseGroupVersion(version.GroupVersion)
 		for i := 0; i < len(version.APIResources); {
-			addflag := (filter.GetFilterPattern() && filter.GetFilterSet().Contains(version.APIResources[i].Name)) || (!filter.GetFilterPattern() && !filter.GetFilterSet().Contains(version.APIResources[i].Name))
-			if !addflag {
-				fileLogger.Infof("filt resouce: kind:tree name:%s handle:excluded", version.APIResources[i].Name)
+			if !keep(gv.WithResource(version.APIResources[i].Name), &version.APIResources[i]) {
 				version.APIResources = append(version.APIResources[:i], version.APIResources[i+1:]...)
 			} else {
-				fileLogger.Infof("filt resouce: kind:tree name:%s handle:included", version.APIResources[i].Name)
 				i++
 			}
 		}
@@ -438,117 +532,50 @@ func filtrateResource(groupAndVersions []*metav1.APIResourceList, filter tree.Fi
 			j++
 		}
 	}
+	return groupAndVersions
 }
 
-func preHandleNamespaceFilter(filters map[string]tree.Filter) {
-	defaultFilter := defaultNamespaceFilter()
+func preHandleNamespaceFilter(filters map[string]tree.Filter, defaultFilters *k8sfilter.DefaultFilters) {
+	defaultFilter := defaultFilters.NamespaceFilter()
 	if filter, ok := filters[immobile.NamespaceKind]; ok {
-		if !filter.GetFilterPattern() {
-			for ns := range defaultFilter.GetFilterSet().Iter() {
-				filter.GetFilterSet().Add(ns)
-			}
-		} else {
-			for ns := range defaultFilter.GetFilterSet().Iter() {
-				filter.GetFilterSet().Remove(ns)
-			}
-		}
+		mergeDefaultFilter(filter, defaultFilter)
 	} else if !ok {
 		filters[immobile.NamespaceKind] = defaultFilter
 	}
 }
 
-func preHandleResourceFilter(filters map[string]tree.Filter) {
-	defaultFilter := defaultResourceFilter()
+func preHandleResourceFilter(filters map[string]tree.Filter, defaultFilters *k8sfilter.DefaultFilters) {
+	defaultFilter := defaultFilters.ResourceFilter()
 	if filter, ok := filters[immobile.ResourceKind]; ok {
-		if !filter.GetFilterPattern() {
-			for ns := range defaultFilter.GetFilterSet().Iter() {
-				filter.GetFilterSet().Add(ns)
-			}
-		} else {
-			for ns := range defaultFilter.GetFilterSet().Iter() {
-				filter.GetFilterSet().Remove(ns)
-			}
-		}
+		mergeDefaultFilter(filter, defaultFilter)
 	} else if !ok {
-		filters[immobile.ResourceKind] = defaultResourceFilter()
+		filters[immobile.ResourceKind] = defaultFilter
 	}
 }
 
-func preHandleObjectFilter(object *unstructured.Unstructured, gvr *schema.GroupVersionResource) bool {
-	addFlag := true
-	defaultObjectFilter := defaultObjectFilter()
-	if filter, ok := defaultObjectFilter[gvr.Resource]; ok {
-		for element := range filter.GetFilterSet().Iter() {
-			substring := element.(string)
-			if strings.Contains(object.GetName(), substring) {
-				addFlag = false
-			}
+// mergeDefaultFilter keeps the default exclusions out of a filter, they are added to an exclude filter
+// and become exceptions of an include filter, so a pattern such as kube-* does not include them either.
+func mergeDefaultFilter(filter, defaultFilter tree.Filter) {
+	if !filter.GetFilterPattern() {
+		for name := range defaultFilter.GetFilterSet().Iter() {
+			filter.GetFilterSet().Add(name)
 		}
+		return
 	}
-	return addFlag
-}
-
-func defaultNamespaceFilter() tree.Filter {
-	filter := &k8sfilter.KubernetesResourceFilter{
-		Kind:              immobile.NamespaceKind,
-		ResourceInclude:   false,
-		ResourceFilterSet: mapset.NewSet(),
-	}
-
-	list := []string{"kube-system", "kube-public", "kube-node-lease"}
-
-	for _, ns := range list {
-		filter.ResourceFilterSet.Add(ns)
-	}
-	return filter
-}
-
-func defaultResourceFilter() tree.Filter {
-	filter := &k8sfilter.KubernetesResourceFilter{
-		Kind:              immobile.ResourceKind,
-		ResourceInclude:   false,
-		ResourceFilterSet: mapset.NewSet(),
-	}
-
-	list := []string{"events", "pods", "endpointslices"}
-
-	for _, ns := range list {
-		filter.ResourceFilterSet.Add(ns)
-	}
-	return filter
-}
-
-func defaultObjectFilter() map[string]tree.Filter {
-	secret := "secrets"
-
-	objectFilters := map[string]tree.Filter{}
 
-	secretFilter := &k8sfilter.KubernetesResourceFilter{
-		Kind:              secret,
-		ResourceInclude:   false,
-		ResourceFilterSet: mapset.NewSet(),
+	resourceFilter, ok := filter.(*k8sfilter.KubernetesResourceFilter)
+	if !ok {
+		for name := range defaultFilter.GetFilterSet().Iter() {
+			filter.GetFilterSet().Remove(name)
+		}
+		return
 	}
-
-	secretList := []string{"default-token"}
-
-	for _, object := range secretList {
-		secretFilter.ResourceFilterSet.Add(object)
+	if resourceFilter.ExcludeFilterSet == nil {
+		resourceFilter.ExcludeFilterSet = mapset.NewSet()
 	}
-
-	objectFilters[secret] = secretFilter
-
-	return objectFilters
-}
-
-func defaultIncludeClusterResource() mapset.Set {
-	defaultIncludeResource := []string{"namespaces", "persistentvolumes"}
-
-	set := mapset.NewSet()
-	for _, resource := range defaultIncludeResource {
-		set.Add(resource)
+	for name := range defaultFilter.GetFilterSet().Iter() {
+		resourceFilter.ExcludeFilterSet.Add(name)
 	}
-
-	return set
 }
 
 func preHandleObjectBeforeCreate(object *tree.Object) {
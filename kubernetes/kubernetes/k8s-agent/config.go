@@ -2,6 +2,7 @@ package k8s_agent
 
 import (
 	"errors"
+	k8sfilter "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-filter"
 	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
 	utillog "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
 	"k8s.io/client-go/discovery"
@@ -18,6 +19,9 @@ type ApiServerConfig struct {
 	KubernetesConfig    string
 	ServiceAccountToken string
 	AccessType          string
+	// DefaultFiltersConfig is the file which the default exclusions of the backups are loaded from,
+	// the built-in default exclusions are used if it is empty.
+	DefaultFiltersConfig string
 }
 
 func (config *ApiServerConfig) AgentInit() (tree.Agent, error) {
@@ -75,11 +79,21 @@ func (config *ApiServerConfig) AgentInit() (tree.Agent, error) {
 		return nil, err
 	}
 
+	defaultFilters := k8sfilter.BuiltinDefaultFilters()
+	if len(config.DefaultFiltersConfig) != 0 {
+		defaultFilters, err = k8sfilter.LoadDefaultFilters(config.DefaultFiltersConfig)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+	}
+
 	client := &KubernetesAgent{
 		ConfigObject:    configObject,
 		DynamicClient:   dynamicClient,
 		DiscoveryClient: discoveryClient,
 		ClientSet:       clientSet,
+		DefaultFilters:  defaultFilters,
 	}
 
 	return client, err
@@ -0,0 +1,126 @@
+package k8s_filter
+
+import (
+	mapset "github.com/deckarep/golang-set"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
+	"os"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultFilters are the exclusions which every backup applies on top of its own filters. A list which is nil
+// falls back to the built-in default, an empty list clears it.
+type DefaultFilters struct {
+	// ExcludedNamespaces are never backed up, even by a backup which includes them.
+	ExcludedNamespaces []string `json:"excludedNamespaces"`
+	// ExcludedResources are never backed up, even by a backup which includes them.
+	ExcludedResources []string `json:"excludedResources"`
+	// IncludedClusterResources are backed up by a backup which does not include the cluster resources.
+	IncludedClusterResources []string `json:"includedClusterResources"`
+	// ExcludedObjects are the names of the objects which are never backed up, keyed by resource.group,
+	// or by the bare resource for the core group.
+	ExcludedObjects map[string][]string `json:"excludedObjects"`
+}
+
+// BuiltinDefaultFilters are the default exclusions which are used when they are not configured.
+func BuiltinDefaultFilters() *DefaultFilters {
+	return &DefaultFilters{
+		ExcludedNamespaces:       []string{"kube-system", "kube-public", "kube-node-lease"},
+		ExcludedResources:        []string{"events", "events.events.k8s.io", "pods", "endpointslices"},
+		IncludedClusterResources: []string{"namespaces", "persistentvolumes"},
+		ExcludedObjects:          map[string][]string{"secrets": {"default-token-*"}},
+	}
+}
+
+// LoadDefaultFilters reads the default exclusions from a yaml or json file, the lists which the file
+// does not set keep their built-in default.
+func LoadDefaultFilters(path string) (*DefaultFilters, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := &DefaultFilters{}
+	if err = yaml.UnmarshalStrict(content, filters); err != nil {
+		return nil, err
+	}
+	return BuiltinDefaultFilters().Merge(filters), nil
+}
+
+// Merge returns the default filters with the lists of the override which are set, the receiver is left untouched.
+func (filters *DefaultFilters) Merge(override *DefaultFilters) *DefaultFilters {
+	merged := *filters
+	if override == nil {
+		return &merged
+	}
+
+	if override.ExcludedNamespaces != nil {
+		merged.ExcludedNamespaces = override.ExcludedNamespaces
+	}
+	if override.ExcludedResources != nil {
+		merged.ExcludedResources = override.ExcludedResources
+	}
+	if override.IncludedClusterResources != nil {
+		merged.IncludedClusterResources = override.IncludedClusterResources
+	}
+	if override.ExcludedObjects != nil {
+		merged.ExcludedObjects = override.ExcludedObjects
+	}
+	return &merged
+}
+
+// NamespaceFilter excludes the default excluded namespaces.
+func (filters *DefaultFilters) NamespaceFilter() *KubernetesResourceFilter {
+	return &KubernetesResourceFilter{
+		Kind:              immobile.NamespaceKind,
+		ResourceInclude:   false,
+		ResourceFilterSet: newStringSet(filters.ExcludedNamespaces),
+	}
+}
+
+// ResourceFilter excludes the default excluded resources.
+func (filters *DefaultFilters) ResourceFilter() *KubernetesResourceFilter {
+	return &KubernetesResourceFilter{
+		Kind:              immobile.ResourceKind,
+		ResourceInclude:   false,
+		ResourceFilterSet: newStringSet(filters.ExcludedResources),
+	}
+}
+
+// ObjectFilter excludes the default excluded objects of their resources.
+func (filters *DefaultFilters) ObjectFilter() *KubernetesObjectFilter {
+	resourceFilters := map[string]*KubernetesResourceFilter{}
+	for resource, names := range filters.ExcludedObjects {
+		resourceFilters[NormalizeGroupResourceKey(resource)] = &KubernetesResourceFilter{
+			Kind:              resource,
+			ResourceInclude:   false,
+			ResourceFilterSet: newStringSet(names),
+		}
+	}
+	return &KubernetesObjectFilter{ResourceFilters: resourceFilters}
+}
+
+// ClusterResourceSet holds the cluster resources which are backed up by default.
+func (filters *DefaultFilters) ClusterResourceSet() mapset.Set {
+	return newStringSet(filters.IncludedClusterResources)
+}
+
+// DefaultFilters is handed to the kubernetes agent as the default exclusions of a single backup.
+func (filters *DefaultFilters) GetFilterKind() string {
+	return immobile.DefaultFiltersKind
+}
+
+func (filters *DefaultFilters) GetFilterPattern() bool {
+	return false
+}
+
+func (filters *DefaultFilters) GetFilterSet() mapset.Set {
+	return newStringSet(filters.ExcludedNamespaces).Union(newStringSet(filters.ExcludedResources))
+}
+
+func newStringSet(names []string) mapset.Set {
+	set := mapset.NewSet()
+	for _, name := range names {
+		set.Add(name)
+	}
+	return set
+}
@@ -0,0 +1,52 @@
+package k8s_filter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadDefaultFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default-filters.yaml")
+	content := "excludedResources:\n- events\nincludedClusterResources: []\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	filters, err := LoadDefaultFilters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builtin := BuiltinDefaultFilters()
+	if !reflect.DeepEqual(filters.ExcludedNamespaces, builtin.ExcludedNamespaces) {
+		t.Errorf("ExcludedNamespaces = %v, want the built-in %v", filters.ExcludedNamespaces, builtin.ExcludedNamespaces)
+	}
+	if !reflect.DeepEqual(filters.ExcludedResources, []string{"events"}) {
+		t.Errorf("ExcludedResources = %v, want [events]", filters.ExcludedResources)
+	}
+	if filters.ClusterResourceSet().Cardinality() != 0 {
+		t.Errorf("IncludedClusterResources = %v, want them cleared", filters.IncludedClusterResources)
+	}
+	if filters.ObjectFilter().Selects("", "secrets", "default-token-abcde") {
+		t.Errorf("default-token secrets are selected, want them excluded by the built-in object filter")
+	}
+
+	objects := filters.Merge(&DefaultFilters{ExcludedObjects: map[string][]string{"deployments.apps": {"web"}, "batch/jobs": {"init-*"}}})
+	if objects.ObjectFilter().Selects("apps", "deployments", "web") || objects.ObjectFilter().Selects("batch", "jobs", "init-db") {
+		t.Errorf("objects keyed by resource.group or group/resource are selected, want them excluded")
+	}
+	if !objects.ObjectFilter().Selects("", "secrets", "default-token-abcde") {
+		t.Errorf("default-token secrets are excluded, want the override to replace the excluded objects")
+	}
+
+	override := filters.Merge(&DefaultFilters{ExcludedResources: []string{}})
+	if !override.ResourceFilter().Selects("events") || filters.ResourceFilter().Selects("events") {
+		t.Errorf("Merge has not replaced the excluded resources of the copy only")
+	}
+
+	if _, err = LoadDefaultFilters(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("LoadDefaultFilters of a missing file succeeded")
+	}
+}
@@ -2,12 +2,19 @@ package k8s_filter
 
 import (
 	mapset "github.com/deckarep/golang-set"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"strings"
 )
 
+// KubernetesResourceFilter includes or excludes names by the entries of ResourceFilterSet, which are exact names,
+// glob patterns or regexes between slashes. ExcludeFilterSet holds the exceptions of an include filter.
 type KubernetesResourceFilter struct {
 	Kind              string
 	ResourceInclude   bool
 	ResourceFilterSet mapset.Set
+	ExcludeFilterSet  mapset.Set
 }
 
 func (filter *KubernetesResourceFilter) GetFilterKind() string {
@@ -21,3 +28,85 @@ func (filter *KubernetesResourceFilter) GetFilterPattern() bool {
 func (filter *KubernetesResourceFilter) GetFilterSet() mapset.Set {
 	return filter.ResourceFilterSet
 }
+
+func (filter *KubernetesResourceFilter) Selects(name string) bool {
+	if !filter.ResourceInclude {
+		return !MatchAny(filter.ResourceFilterSet, name)
+	}
+	return MatchAny(filter.ResourceFilterSet, name) && !MatchAny(filter.ExcludeFilterSet, name)
+}
+
+// KubernetesLabelFilter only selects the objects whose labels match the selector, in every namespace and resource.
+type KubernetesLabelFilter struct {
+	Selector labels.Selector
+}
+
+func (filter *KubernetesLabelFilter) GetFilterKind() string {
+	return immobile.LabelSelectorKind
+}
+
+func (filter *KubernetesLabelFilter) GetFilterPattern() bool {
+	return true
+}
+
+func (filter *KubernetesLabelFilter) GetFilterSet() mapset.Set {
+	return mapset.NewSet(filter.Selector.String())
+}
+
+func (filter *KubernetesLabelFilter) Matches(object *unstructured.Unstructured) bool {
+	return object != nil && filter.Selector.Matches(labels.Set(object.GetLabels()))
+}
+
+// ExcludeFromBackupAnnotation opts a single object out of every backup when it is set to true.
+const ExcludeFromBackupAnnotation = "boxroom.io/exclude-from-backup"
+
+// IsExcludedFromBackup tells whether the object carries the opt-out annotation.
+func IsExcludedFromBackup(object *unstructured.Unstructured) bool {
+	return object != nil && object.GetAnnotations()[ExcludeFromBackupAnnotation] == "true"
+}
+
+// KubernetesObjectFilter includes or excludes the objects of single resources by name, the filters are keyed
+// by resource.group, and by the bare resource name for the core group.
+type KubernetesObjectFilter struct {
+	ResourceFilters map[string]*KubernetesResourceFilter
+}
+
+func (filter *KubernetesObjectFilter) GetFilterKind() string {
+	return immobile.ObjectFilterKind
+}
+
+func (filter *KubernetesObjectFilter) GetFilterPattern() bool {
+	return true
+}
+
+func (filter *KubernetesObjectFilter) GetFilterSet() mapset.Set {
+	set := mapset.NewSet()
+	for key := range filter.ResourceFilters {
+		set.Add(key)
+	}
+	return set
+}
+
+// Selects tells whether the object of the resource is kept, the objects of a resource without a filter are all kept.
+func (filter *KubernetesObjectFilter) Selects(group, resource, name string) bool {
+	resourceFilter, ok := filter.ResourceFilters[GroupResourceKey(group, resource)]
+	return !ok || resourceFilter.Selects(name)
+}
+
+// GroupResourceKey is the key of a resource in an object filter: resource.group like the resource filters,
+// or resource for the core group.
+func GroupResourceKey(group, resource string) string {
+	if len(group) == 0 {
+		return resource
+	}
+	return resource + "." + group
+}
+
+// NormalizeGroupResourceKey turns a key in the group/resource notation, which the object filters were keyed
+// by before, into resource.group and leaves any other key as it is.
+func NormalizeGroupResourceKey(key string) string {
+	if group, resource, found := strings.Cut(key, "/"); found {
+		return GroupResourceKey(group, resource)
+	}
+	return key
+}
@@ -4,6 +4,7 @@ import (
 	mapset "github.com/deckarep/golang-set"
 	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
 	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func GetClusterResourceFilter(include bool) tree.Filter {
@@ -16,6 +17,24 @@ func GetClusterResourceFilter(include bool) tree.Filter {
 	return filter
 }
 
+// GetLabelSelectorFilter selects the objects whose labels match the selector.
+func GetLabelSelectorFilter(selector labels.Selector) tree.Filter {
+	return &KubernetesLabelFilter{
+		Selector: selector,
+	}
+}
+
+// GetObjectFilter filters the objects of the resources by the name filters keyed by resource.group.
+func GetObjectFilter(resourceFilters map[string]*KubernetesResourceFilter) tree.Filter {
+	filters := map[string]*KubernetesResourceFilter{}
+	for key, filter := range resourceFilters {
+		filters[NormalizeGroupResourceKey(key)] = filter
+	}
+	return &KubernetesObjectFilter{
+		ResourceFilters: filters,
+	}
+}
+
 func GetTreeRootFilter(clusterName, treeKind, treeName string) tree.Filter {
 	filter := &KubernetesResourceFilter{
 		Kind:              immobile.RootKind,
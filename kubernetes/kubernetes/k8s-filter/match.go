@@ -0,0 +1,93 @@
+package k8s_filter
+
+import (
+	mapset "github.com/deckarep/golang-set"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// regexps caches the compiled regex entries, the same filters are matched against every name of a backup.
+var regexps sync.Map
+
+// IsRegexPattern tells whether a filter entry is a regular expression, which is written between slashes.
+// Kubernetes names never contain a slash, so such an entry can not be meant as a name.
+func IsRegexPattern(entry string) bool {
+	return len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/")
+}
+
+// IsGlobPattern tells whether a filter entry is a glob pattern, e.g. team-* or *.cert-manager.io.
+func IsGlobPattern(entry string) bool {
+	return !IsRegexPattern(entry) && strings.ContainsAny(entry, "*?[")
+}
+
+// CompilePattern compiles a regex entry into an expression which has to match the whole name.
+func CompilePattern(entry string) (*regexp.Regexp, error) {
+	if compiled, ok := regexps.Load(entry); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+
+	compiled, err := regexp.Compile("^(?:" + entry[1:len(entry)-1] + ")$")
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(entry, compiled)
+	return compiled, nil
+}
+
+// ValidatePattern reports a regex or a glob entry which can not be compiled, an exact name is always valid.
+func ValidatePattern(entry string) error {
+	switch {
+	case IsRegexPattern(entry):
+		_, err := CompilePattern(entry)
+		return err
+	case IsGlobPattern(entry):
+		_, err := path.Match(entry, "")
+		return err
+	}
+	return nil
+}
+
+// MatchEntry matches a name against a single filter entry, which is an exact name, a glob pattern or a regex.
+// An invalid pattern matches nothing.
+func MatchEntry(entry, name string) bool {
+	switch {
+	case IsRegexPattern(entry):
+		compiled, err := CompilePattern(entry)
+		return err == nil && compiled.MatchString(name)
+	case IsGlobPattern(entry):
+		matched, err := path.Match(entry, name)
+		return err == nil && matched
+	}
+	return entry == name
+}
+
+// MatchAny tells whether one of the entries of the set matches the name.
+func MatchAny(set mapset.Set, name string) bool {
+	if set == nil {
+		return false
+	}
+	if set.Contains(name) {
+		return true
+	}
+
+	for element := range set.Iter() {
+		entry, ok := element.(string)
+		if ok && MatchEntry(entry, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Selects tells whether the filter keeps the name, an include filter keeps the names which match one of its entries
+// and an exclude filter keeps the names which match none of them. It is shared by the kubernetes agent and the
+// storage agent so a name is filtered the same way on backup and on restore.
+func Selects(filter tree.Filter, name string) bool {
+	if resourceFilter, ok := filter.(*KubernetesResourceFilter); ok {
+		return resourceFilter.Selects(name)
+	}
+	return filter.GetFilterPattern() == MatchAny(filter.GetFilterSet(), name)
+}
@@ -0,0 +1,55 @@
+package k8s_filter
+
+import (
+	mapset "github.com/deckarep/golang-set"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
+	"testing"
+)
+
+func TestMatchEntry(t *testing.T) {
+	tests := []struct {
+		entry, name string
+		want        bool
+	}{
+		{"team-a", "team-a", true},
+		{"team-a", "team-ab", false},
+		{"team-*", "team-payments", true},
+		{"team-*", "other-team", false},
+		{"*.cert-manager.io", "certificates.cert-manager.io", true},
+		{"/team-(a|b)/", "team-b", true},
+		{"/team-(a|b)/", "team-bc", false},
+		{"/team-[/", "team-[", false},
+		{"[", "[", false},
+	}
+
+	for _, test := range tests {
+		if got := MatchEntry(test.entry, test.name); got != test.want {
+			t.Errorf("MatchEntry(%q, %q) = %v, want %v", test.entry, test.name, got, test.want)
+		}
+	}
+}
+
+func TestSelects(t *testing.T) {
+	include := &KubernetesResourceFilter{
+		Kind:              immobile.NamespaceKind,
+		ResourceInclude:   true,
+		ResourceFilterSet: mapset.NewSet("team-*"),
+		ExcludeFilterSet:  mapset.NewSet("team-legacy"),
+	}
+	exclude := &KubernetesResourceFilter{
+		Kind:              immobile.NamespaceKind,
+		ResourceInclude:   false,
+		ResourceFilterSet: mapset.NewSet("/kube-.*/"),
+	}
+
+	for name, want := range map[string]bool{"team-a": true, "team-legacy": false, "default": false} {
+		if got := Selects(include, name); got != want {
+			t.Errorf("include filter: Selects(%q) = %v, want %v", name, got, want)
+		}
+	}
+	for name, want := range map[string]bool{"kube-system": false, "default": true} {
+		if got := Selects(exclude, name); got != want {
+			t.Errorf("exclude filter: Selects(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package k8s_filter
+
+import (
+	mapset "github.com/deckarep/golang-set"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"strings"
+)
+
+// A resource filter entry follows the kubectl notation, it is the resource name, resource.group or
+// resource.version.group, e.g. events, events.events.k8s.io or deployments.v1.apps. Resource names never
+// contain a dot, so an exact entry with a dot always names the group of the resource.
+//
+// A bare resource name is resolved against the discovered resources the way kubectl resolves it: it refers to
+// the resource of the core group if there is one, and to the resources of that name in every other group if not.
+// Glob patterns and regexes are matched against every notation of a resource, e.g. *.cert-manager.io.
+
+// resourceNames are the notations which a filter entry can refer to a resource by.
+func resourceNames(gvr schema.GroupVersionResource) []string {
+	if len(gvr.Group) == 0 {
+		return []string{gvr.Resource}
+	}
+	return []string{
+		gvr.Resource,
+		gvr.Resource + "." + gvr.Group,
+		gvr.Resource + "." + gvr.Version + "." + gvr.Group,
+	}
+}
+
+// MatchResourceEntry matches a resource against a single filter entry, coreResources holds the names of
+// the discovered resources of the core group which bare names are resolved to first.
+func MatchResourceEntry(entry string, gvr schema.GroupVersionResource, coreResources mapset.Set) bool {
+	if !IsRegexPattern(entry) && !IsGlobPattern(entry) && !strings.Contains(entry, ".") {
+		if entry != gvr.Resource {
+			return false
+		}
+		return len(gvr.Group) == 0 || coreResources == nil || !coreResources.Contains(entry)
+	}
+
+	for _, name := range resourceNames(gvr) {
+		if MatchEntry(entry, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAnyResource tells whether one of the entries of the set matches the resource.
+func MatchAnyResource(set mapset.Set, gvr schema.GroupVersionResource, coreResources mapset.Set) bool {
+	if set == nil {
+		return false
+	}
+
+	for element := range set.Iter() {
+		entry, ok := element.(string)
+		if ok && MatchResourceEntry(entry, gvr, coreResources) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectsResource tells whether the resource filter keeps the resource, like Selects does for a name.
+func SelectsResource(filter tree.Filter, gvr schema.GroupVersionResource, coreResources mapset.Set) bool {
+	if resourceFilter, ok := filter.(*KubernetesResourceFilter); ok {
+		if !resourceFilter.ResourceInclude {
+			return !MatchAnyResource(resourceFilter.ResourceFilterSet, gvr, coreResources)
+		}
+		return MatchAnyResource(resourceFilter.ResourceFilterSet, gvr, coreResources) &&
+			!MatchAnyResource(resourceFilter.ExcludeFilterSet, gvr, coreResources)
+	}
+	return filter.GetFilterPattern() == MatchAnyResource(filter.GetFilterSet(), gvr, coreResources)
+}
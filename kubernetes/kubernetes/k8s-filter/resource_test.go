@@ -0,0 +1,40 @@
+package k8s_filter
+
+import (
+	mapset "github.com/deckarep/golang-set"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"testing"
+)
+
+func TestMatchResourceEntry(t *testing.T) {
+	coreEvents := schema.GroupVersionResource{Version: "v1", Resource: "events"}
+	events := schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1", Resource: "events"}
+	certificates := schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+	slices := schema.GroupVersionResource{Group: "discovery.k8s.io", Version: "v1", Resource: "endpointslices"}
+	coreResources := mapset.NewSet("events", "pods")
+
+	tests := []struct {
+		entry string
+		gvr   schema.GroupVersionResource
+		want  bool
+	}{
+		{"events", coreEvents, true},
+		{"events", events, false},
+		{"events.events.k8s.io", events, true},
+		{"events.events.k8s.io", coreEvents, false},
+		{"events.v1.events.k8s.io", events, true},
+		{"events.v1beta1.events.k8s.io", events, false},
+		{"endpointslices", slices, true},
+		{"certificates.cert-manager.io", certificates, true},
+		{"certificates.acme.example.com", certificates, false},
+		{"*.cert-manager.io", certificates, true},
+		{"*.cert-manager.io", events, false},
+		{"/events(\\.events\\.k8s\\.io)?/", events, true},
+	}
+
+	for _, test := range tests {
+		if got := MatchResourceEntry(test.entry, test.gvr, coreResources); got != test.want {
+			t.Errorf("MatchResourceEntry(%q, %v) = %v, want %v", test.entry, test.gvr, got, test.want)
+		}
+	}
+}
@@ -8,6 +8,9 @@ const (
 	ResourceKind          = "ResourceKind"
 	NamespaceKind         = "NamespaceKind"
 	ObjectKind            = "ObjectKind"
+	LabelSelectorKind     = "LabelSelectorKind"
+	ObjectFilterKind      = "ObjectFilterKind"
+	DefaultFiltersKind    = "DefaultFiltersKind"
 	ClusterLevelNamespace = "cluster"
 	RootName              = "k8s-cluster-a-1"
 	TreeBackupKind        = "backup"
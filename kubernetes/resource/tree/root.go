@@ -73,3 +73,17 @@ func (root *KubernetesRoot) ListChildren() []string {
 	}
 	return childrenList
 }
+
+func (root *KubernetesRoot) CountObjects() int {
+	count := 0
+	for _, group := range root.Groups {
+		for _, version := range group.Versions {
+			for _, resource := range version.Resources {
+				for _, namespace := range resource.Namespaces {
+					count += len(namespace.Objects)
+				}
+			}
+		}
+	}
+	return count
+}
@@ -5,14 +5,19 @@ import "encoding/json"
 type Status interface {
 	SetStatus(status string)
 	AddFailedObjects(name string, err error)
+	AddSucceededObjects(num int)
+	GetStatus() string
+	GetFailedObjects() map[string]error
+	GetSucceededObjects() int
 	CovertStructToJson() ([]byte, error)
 	CovertJsonToStruct(jsonDefinition []byte) error
 }
 
 type MissionStatus struct {
-	MissionKind   string
-	Status        string
-	FailedObjects map[string]error
+	MissionKind      string
+	Status           string
+	SucceededObjects int
+	FailedObjects    map[string]error
 }
 
 func (m *MissionStatus) CovertStructToJson() ([]byte, error) {
@@ -30,3 +35,19 @@ func (m *MissionStatus) SetStatus(status string) {
 func (m *MissionStatus) AddFailedObjects(name string, err error) {
 	m.FailedObjects[name] = err
 }
+
+func (m *MissionStatus) AddSucceededObjects(num int) {
+	m.SucceededObjects += num
+}
+
+func (m *MissionStatus) GetStatus() string {
+	return m.Status
+}
+
+func (m *MissionStatus) GetFailedObjects() map[string]error {
+	return m.FailedObjects
+}
+
+func (m *MissionStatus) GetSucceededObjects() int {
+	return m.SucceededObjects
+}
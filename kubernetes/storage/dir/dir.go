@@ -1,14 +1,14 @@
 package dir
 
 import (
-	"boxroom/resource/immobile"
-	"boxroom/resource/tree"
-	globleimmobile "boxroom/util/globle-immobile"
-	utilfunc "boxroom/util/util-func"
-	utillog "boxroom/util/util-log"
 	"bytes"
 	"errors"
 	"fmt"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
+	globleimmobile "github.io/misskaori/boxroom-crd/kubernetes/util/globle-immobile"
+	utilfunc "github.io/misskaori/boxroom-crd/kubernetes/util/util-func"
+	utillog "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
 	"io/fs"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"path/filepath"
@@ -23,6 +23,7 @@ type StorageDirDefinition interface {
 	GetAssistLogRemoteDir(root *tree.KubernetesRoot) (string, string)
 	GetAssistLogLocalZipDir(localLogName string, localStatusLogName string) (string, string, error)
 	GetRemoteStoragePrefixAndDelimiter(root *tree.KubernetesRoot) (string, string)
+	GetRemoteTreePrefix(root *tree.KubernetesRoot) string
 	GetDownLoadDirMap(root *tree.KubernetesRoot) (map[string]string, string, string, error)
 	ParseCommonPrefix(prefix string) string
 	PreHandleRestoreRoot(root *tree.KubernetesRoot)
@@ -35,7 +36,7 @@ type DefaultStorageDirDefinition struct {
 
 func (dir *DefaultStorageDirDefinition) PreHandleRestoreRoot(root *tree.KubernetesRoot) {
 	if root.TreeKind == immobile.TreeRestoreKind {
-		root.TreeName = immobile.TreeRestoreKind + time.Now().Format(globleimmobile.TimestampFormat) + "-" + root.TreeName
+		root.TreeName = GetTimestampTreeName(immobile.TreeRestoreKind, time.Now(), root.TreeName)
 	}
 }
 
@@ -52,8 +53,7 @@ func (dir *DefaultStorageDirDefinition) TransformResourceTreeToLocal(root *tree.
 		}
 	}
 	if len(root.TreeName) == 0 {
-		nowTime := time.Now().Format(globleimmobile.TimestampFormat)
-		root.TreeName = root.TreeKind + nowTime
+		root.TreeName = GetTimestampTreeName(root.TreeKind, time.Now(), "")
 	}
 
 	localWorkDir, localStorageDir := getLocalStorageDir(root)
@@ -158,6 +158,10 @@ func (dir *DefaultStorageDirDefinition) GetRemoteStoragePrefixAndDelimiter(root
 	return dirOperator.GenerateDirPath(root.Name, root.TreeKind) + "/", "/"
 }
 
+func (dir *DefaultStorageDirDefinition) GetRemoteTreePrefix(root *tree.KubernetesRoot) string {
+	return getRemoteStorageDir(root) + "/"
+}
+
 func (dir *DefaultStorageDirDefinition) GetDownLoadDirMap(root *tree.KubernetesRoot) (map[string]string, string, string, error) {
 	dirOperator := utilfunc.NewWorkDirOperator()
 
@@ -177,6 +181,15 @@ func (dir *DefaultStorageDirDefinition) ParseCommonPrefix(prefix string) string
 	return filepath.Base(prefix)
 }
 
+// GetTimestampTreeName names a tree by its kind and a timestamp, the suffix is appended after a dash if it is not empty.
+func GetTimestampTreeName(treeKind string, timestamp time.Time, suffix string) string {
+	treeName := treeKind + timestamp.Format(globleimmobile.TimestampFormat)
+	if len(suffix) != 0 {
+		treeName = treeName + "-" + suffix
+	}
+	return treeName
+}
+
 func buildResourceTree(root *tree.KubernetesRoot, resourceMap map[string]map[string][]byte) error {
 	dirOperator := utilfunc.NewWorkDirOperator()
 
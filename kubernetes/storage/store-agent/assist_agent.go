@@ -79,22 +79,15 @@ func (agent *AssistLogStoreAgent) UploadLocalLogger(root *tree.KubernetesRoot) e
 		return err
 	}
 
-	localZipLogFile, err := fileOperator.OpenFile(localZipLogDir)
+	err = uploadLocalFile(agent.Client, localZipLogDir, remoteLoggerDir)
 	if err != nil {
 		return err
 	}
 
-	localZipStatusLogFile, err := fileOperator.OpenFile(localZipStatusLogDir)
+	err = uploadLocalFile(agent.Client, localZipStatusLogDir, remoteStatusLoggerDir)
 	if err != nil {
 		return err
 	}
 
-	err = agent.Client.UploadObject(remoteLoggerDir, localZipLogFile)
-	if err != nil {
-		return err
-	}
-
-	err = agent.Client.UploadObject(remoteStatusLoggerDir, localZipStatusLogFile)
-
 	return nil
 }
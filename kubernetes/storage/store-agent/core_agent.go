@@ -1,12 +1,12 @@
 package store_agent
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	mapset "github.com/deckarep/golang-set"
 	"github.com/sirupsen/logrus"
+	k8sfilter "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-filter"
 	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
 	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
 	"github.io/misskaori/boxroom-crd/kubernetes/storage/dir"
@@ -14,6 +14,8 @@ import (
 	globleimmobile "github.io/misskaori/boxroom-crd/kubernetes/util/globle-immobile"
 	utilfunc "github.io/misskaori/boxroom-crd/kubernetes/util/util-func"
 	utillog "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
+	"io"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var log = new(utillog.NewLog).GetLogger()
@@ -21,6 +23,21 @@ var log = new(utillog.NewLog).GetLogger()
 type CoreStoreAgent struct {
 	Client        storeclient.StoreClient
 	DirDefinition dir.StorageDirDefinition
+	// Replicas receive a copy of every resource tree which is applied to Client,
+	// a replica which fails records its error and does not fail the apply.
+	Replicas []*StoreTarget
+	// CoreResources are the names of the discovered resources of the core group of the cluster which is restored,
+	// the bare resource names of the filters are resolved against them. Without them a bare name refers to the
+	// resources of that name in every group.
+	CoreResources mapset.Set
+}
+
+// StoreTarget is a further storage which resource trees are replicated to.
+type StoreTarget struct {
+	Name   string
+	Client storeclient.StoreClient
+	// Err is the error of the last upload to the target, it is nil once the upload has succeeded.
+	Err error
 }
 
 func (agent *CoreStoreAgent) GetResourceTree(root *tree.KubernetesRoot, filters map[string]tree.Filter, ctx context.Context) (*tree.KubernetesRoot, error) {
@@ -69,7 +86,7 @@ func (agent *CoreStoreAgent) GetResourceTree(root *tree.KubernetesRoot, filters
 		}
 	}()
 
-	filtrateResources(root, filters)
+	filtrateResources(root, filters, agent.CoreResources)
 
 	return root, nil
 }
@@ -114,17 +131,112 @@ func (agent *CoreStoreAgent) ApplyResourceTree(root *tree.KubernetesRoot, ctx co
 		return err
 	}
 
-	for localFile, remoteFile := range storageMap {
-		fileReader, err := fileOperator.OpenFile(localFile)
+	err = uploadStorageMap(agent.Client, storageMap)
+	if err != nil {
+		fileLogger.Error(err)
+		return err
+	}
+
+	for _, replica := range agent.Replicas {
+		replica.Err = agent.applyToReplica(root, replica, storageMap)
+		if replica.Err != nil {
+			fileLogger.Errorf("failed to replicate %s %s to %s: %v", root.TreeKind, root.TreeName, replica.Name, replica.Err)
+			continue
+		}
+		fileLogger.Infof("%s %s is replicated to %s", root.TreeKind, root.TreeName, replica.Name)
+	}
+
+	return nil
+}
+
+func (agent *CoreStoreAgent) applyToReplica(root *tree.KubernetesRoot, replica *StoreTarget, storageMap map[string]string) error {
+	replicaAgent := &CoreStoreAgent{
+		Client:        replica.Client,
+		DirDefinition: agent.DirDefinition,
+	}
+
+	exist, err := replicaAgent.CheckRemoteStorageExist(root)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return fmt.Errorf("the storage has already exist: storage kind: %s storage name: %s", root.TreeKind, root.TreeName)
+	}
+
+	return uploadStorageMap(replica.Client, storageMap)
+}
+
+// CopyRemoteStorage copies the remote tree of the root from Client to the target storage object by object.
+// Every object passes through a local file because a store client needs the size of an upload,
+// the partial copy is removed from the target when the copy fails. The log archives are left out like
+// in ApplyResourceTree, so a replica holds the same objects whenever it has been added.
+func (agent *CoreStoreAgent) CopyRemoteStorage(root *tree.KubernetesRoot, target storeclient.StoreClient) error {
+	targetAgent := &CoreStoreAgent{
+		Client:        target,
+		DirDefinition: agent.DirDefinition,
+	}
+
+	exist, err := agent.CheckRemoteStorageExist(root)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if !exist {
+		e := fmt.Sprintf("there is no such %s for cluster %s, %s name is: %s", root.TreeKind, root.Name, root.TreeKind, root.TreeName)
+		log.Error(e)
+		return errors.New(e)
+	}
+
+	exist, err = targetAgent.CheckRemoteStorageExist(root)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if exist {
+		e := fmt.Sprintf("the storage has already exist: storage kind: %s storage name: %s", root.TreeKind, root.TreeName)
+		log.Error(e)
+		return errors.New(e)
+	}
+
+	keys, err := agent.Client.ListObjects(agent.DirDefinition.GetRemoteTreePrefix(root))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	dirOperator := utilfunc.NewWorkDirOperator()
+	fileOperator := utilfunc.NewWorkDirFileOperator()
+	localWorkDir := dirOperator.GenerateDirPath(globleimmobile.WorkDir, utilfunc.RandStr(30))
+	defer func() {
+		err := fileOperator.DeleteDirOrFile(localWorkDir)
 		if err != nil {
-			fileLogger.Error(err)
-			return err
+			log.Error(err)
+		}
+	}()
+
+	loggerRemoteDir, statusLoggerRemoteDir := agent.DirDefinition.GetAssistLogRemoteDir(root)
+	for _, key := range keys {
+		if key == loggerRemoteDir || key == statusLoggerRemoteDir {
+			continue
+		}
+
+		localFile := dirOperator.GenerateDirPath(localWorkDir, key)
+		err = agent.GetRemoteStorage(key, localFile)
+		if err == nil {
+			err = uploadLocalFile(target, localFile, key)
 		}
-		err = agent.Client.UploadObject(remoteFile, fileReader)
 		if err != nil {
-			fileLogger.Error(err)
+			log.Error(err)
+			if deleteErr := targetAgent.DeleteRemoteStorage(root); deleteErr != nil {
+				log.Error(deleteErr)
+			}
 			return err
 		}
+
+		err = fileOperator.DeleteDirOrFile(localFile)
+		if err != nil {
+			log.Error(err)
+		}
 	}
 
 	return nil
@@ -161,6 +273,22 @@ func (agent *CoreStoreAgent) ListRemoteStorage(root *tree.KubernetesRoot) (mapse
 	return set, nil
 }
 
+func (agent *CoreStoreAgent) DeleteRemoteStorage(root *tree.KubernetesRoot) error {
+	if len(root.TreeName) == 0 {
+		e := fmt.Sprintf("can not delete a %s without tree name for cluster %s", root.TreeKind, root.Name)
+		log.Error(e)
+		return errors.New(e)
+	}
+
+	err := agent.Client.DeletePrefix(agent.DirDefinition.GetRemoteTreePrefix(root))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
 func (agent *CoreStoreAgent) GetRemoteStorage(remoteFile, localFile string) error {
 	fileOperator := utilfunc.NewWorkDirFileOperator()
 
@@ -169,52 +297,187 @@ func (agent *CoreStoreAgent) GetRemoteStorage(remoteFile, localFile string) erro
 		log.Error(err)
 		return err
 	}
+	defer func() {
+		err = remoteFileReader.Close()
+		if err != nil {
+			log.Error(err)
+		}
+	}()
 
-	buff := bytes.Buffer{}
-	_, err = buff.ReadFrom(remoteFileReader)
+	localFileWriter, err := fileOperator.CreateFile(localFile)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
+	defer func() {
+		err = localFileWriter.Close()
+		if err != nil {
+			log.Error(err)
+		}
+	}()
 
-	localFileReader, err := fileOperator.CreateFile(localFile)
-
-	err = fileOperator.WriteFile(localFileReader, buff.Bytes())
+	_, err = io.Copy(localFileWriter, remoteFileReader)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
 
+	return nil
+}
+
+// uploadStorageMap uploads every local file of the storage map to its remote key.
+func uploadStorageMap(client storeclient.StoreClient, storageMap map[string]string) error {
+	for localFile, remoteFile := range storageMap {
+		err := uploadLocalFile(client, localFile, remoteFile)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadLocalFile streams a local file to the storage without reading it into memory.
+func uploadLocalFile(client storeclient.StoreClient, localFile, remoteFile string) error {
+	fileReader, err := utilfunc.NewWorkDirFileOperator().OpenFile(localFile)
+	if err != nil {
+		return err
+	}
 	defer func() {
-		err = localFileReader.Close()
+		err = fileReader.Close()
 		if err != nil {
 			log.Error(err)
 		}
 	}()
 
-	return nil
+	fileInfo, err := fileReader.Stat()
+	if err != nil {
+		return err
+	}
+
+	return client.UploadObject(remoteFile, fileReader, fileInfo.Size())
 }
 
-func filtrateResources(root *tree.KubernetesRoot, filters map[string]tree.Filter) {
+func filtrateResources(root *tree.KubernetesRoot, filters map[string]tree.Filter, coreResources mapset.Set) {
 	for _, filter := range filters {
+		switch filter.GetFilterKind() {
+		case immobile.ClusterKind:
+			filtrateClusterResources(root, filter)
+			continue
+		case immobile.ResourceKind:
+			filtrateGroupResources(root, filter, coreResources)
+			continue
+		case immobile.LabelSelectorKind:
+			if labelFilter, ok := filter.(*k8sfilter.KubernetesLabelFilter); ok {
+				filtrateObjects(root, func(group *tree.Group, resource *tree.Resource, object *tree.Object) bool {
+					return labelFilter.Matches(object.Definition)
+				})
+			}
+			continue
+		case immobile.ObjectFilterKind:
+			if objectFilter, ok := filter.(*k8sfilter.KubernetesObjectFilter); ok {
+				filtrateObjects(root, func(group *tree.Group, resource *tree.Resource, object *tree.Object) bool {
+					return objectFilter.Selects(group.Name, resource.Name, object.Name)
+				})
+			}
+			continue
+		}
 		deepFiltrateResources(root, filter)
 	}
 }
 
+// filtrateObjects drops the objects which are not kept, and the branches which are left empty.
+func filtrateObjects(root *tree.KubernetesRoot, keep func(group *tree.Group, resource *tree.Resource, object *tree.Object) bool) {
+	for _, group := range root.Groups {
+		for _, version := range group.Versions {
+			for _, resource := range version.Resources {
+				for _, namespace := range resource.Namespaces {
+					for _, object := range namespace.Objects {
+						if !keep(group, resource, object) {
+							log.Infof("delete object from resource tree: group: %s resource: %s namespace: %s name: %s", group.Name, resource.Name, namespace.Name, object.Name)
+							namespace.DeleteChildren(object)
+						}
+					}
+					if len(namespace.Objects) == 0 {
+						resource.DeleteChildren(namespace)
+					}
+				}
+				if len(resource.Namespaces) == 0 {
+					version.DeleteChildren(resource)
+				}
+			}
+			if len(version.Resources) == 0 {
+				group.DeleteChildren(version)
+			}
+		}
+		if len(group.Versions) == 0 {
+			root.DeleteChildren(group)
+		}
+	}
+}
+
+// filtrateGroupResources filters the resources by their name, resource.group or resource.version.group,
+// the bare resource names are resolved against the discovered core resources like on backup. The backup tree
+// can not be used for that, it lacks the core resources which are excluded by default, such as events.
+func filtrateGroupResources(root *tree.KubernetesRoot, filter tree.Filter, coreResources mapset.Set) {
+	for _, group := range root.Groups {
+		for _, version := range group.Versions {
+			for _, resource := range version.Resources {
+				gvr := schema.GroupVersionResource{Group: group.Name, Version: version.Name, Resource: resource.Name}
+				if !k8sfilter.SelectsResource(filter, gvr, coreResources) {
+					log.Infof("delete resources from resource tree: group: %s version: %s name: %s", group.Name, version.Name, resource.Name)
+					version.DeleteChildren(resource)
+				}
+			}
+			if len(version.Resources) == 0 {
+				group.DeleteChildren(version)
+			}
+		}
+		if len(group.Versions) == 0 {
+			root.DeleteChildren(group)
+		}
+	}
+}
+
+func filtrateClusterResources(root *tree.KubernetesRoot, filter tree.Filter) {
+	if filter.GetFilterPattern() {
+		return
+	}
+
+	for _, group := range root.Groups {
+		for _, version := range group.Versions {
+			for _, resource := range version.Resources {
+				if resource.IsCluster {
+					log.Infof("delete cluster resources from resource tree: group: %s version: %s name: %s", group.Name, version.Name, resource.Name)
+					version.DeleteChildren(resource)
+				}
+			}
+			if len(version.Resources) == 0 {
+				group.DeleteChildren(version)
+			}
+		}
+		if len(group.Versions) == 0 {
+			root.DeleteChildren(group)
+		}
+	}
+}
+
 func deepFiltrateResources(resources tree.Resources, filter tree.Filter) {
 	if resources.GetKind() != filter.GetFilterKind() {
 		for _, children := range resources.ListChildren() {
 			deepFiltrateResources(resources.GetChildren(children), filter)
 		}
-		if len(resources.ListChildren()) == 0 {
+		if len(resources.ListChildren()) == 0 && resources.GetParent() != nil {
 			resources.GetParent().DeleteChildren(resources)
 		}
 		return
 	}
 
-	addFlag := (filter.GetFilterPattern() && filter.GetFilterSet().Contains(resources.GetName())) || (!filter.GetFilterPattern() && !filter.GetFilterSet().Contains(resources.GetName()))
+	// cluster level resources are stored under a pseudo namespace, they are not handled by namespace filters
+	if resources.GetKind() == immobile.NamespaceKind && resources.GetName() == immobile.ClusterLevelNamespace {
+		return
+	}
 
-	if !addFlag {
+	if !k8sfilter.Selects(filter, resources.GetName()) {
 		log.Infof("delete resources from resource tree: filter: %v kind: %s name: %s", filter, resources.GetKind(), resources.GetName())
 		resources.GetParent().DeleteChildren(resources)
 	}
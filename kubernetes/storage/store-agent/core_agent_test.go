@@ -0,0 +1,44 @@
+package store_agent
+
+import (
+	mapset "github.com/deckarep/golang-set"
+	k8sfilter "github.io/misskaori/boxroom-crd/kubernetes/kubernetes/k8s-filter"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/immobile"
+	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
+	"testing"
+)
+
+// newBackupTree builds the tree of a backup which has excluded the core events by default,
+// so only events.k8s.io holds a resource named events.
+func newBackupTree() *tree.KubernetesRoot {
+	root := &tree.KubernetesRoot{Kind: immobile.RootKind, Name: immobile.RootName, Groups: map[string]*tree.Group{}}
+	root.AddChildren("").AddChildren("v1").AddChildren("configmaps", false)
+	root.AddChildren("events.k8s.io").AddChildren("v1").AddChildren("events", false)
+	return root
+}
+
+func TestFiltrateGroupResourcesResolvesBareNamesByDiscovery(t *testing.T) {
+	coreResources := mapset.NewSet("configmaps", "events", "secrets")
+
+	// the bare name refers to the core events on backup, so it must not exclude events.k8s.io on restore
+	root := newBackupTree()
+	excluded := &k8sfilter.KubernetesResourceFilter{Kind: immobile.ResourceKind, ResourceFilterSet: mapset.NewSet("events")}
+	filtrateResources(root, map[string]tree.Filter{immobile.ResourceKind: excluded}, coreResources)
+	if _, ok := root.Groups["events.k8s.io"]; !ok {
+		t.Errorf("events.k8s.io was excluded by the bare name events")
+	}
+
+	root = newBackupTree()
+	included := &k8sfilter.KubernetesResourceFilter{Kind: immobile.ResourceKind, ResourceInclude: true, ResourceFilterSet: mapset.NewSet("events")}
+	filtrateResources(root, map[string]tree.Filter{immobile.ResourceKind: included}, coreResources)
+	if len(root.Groups) != 0 {
+		t.Errorf("groups %v are left, want the bare name events to select the core events only", root.ListChildren())
+	}
+
+	root = newBackupTree()
+	qualified := &k8sfilter.KubernetesResourceFilter{Kind: immobile.ResourceKind, ResourceInclude: true, ResourceFilterSet: mapset.NewSet("events.events.k8s.io")}
+	filtrateResources(root, map[string]tree.Filter{immobile.ResourceKind: qualified}, coreResources)
+	if _, ok := root.Groups["events.k8s.io"]; !ok || len(root.Groups) != 1 {
+		t.Errorf("groups %v are left, want events.k8s.io only", root.ListChildren())
+	}
+}
@@ -3,6 +3,7 @@ package store_agent
 import (
 	"errors"
 	"fmt"
+	mapset "github.com/deckarep/golang-set"
 	"github.io/misskaori/boxroom-crd/kubernetes/resource/tree"
 	"github.io/misskaori/boxroom-crd/kubernetes/storage/dir"
 	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
@@ -11,6 +12,8 @@ import (
 type StorageConfig struct {
 	Client        storeclient.StoreClient
 	DirDefinition dir.StorageDirDefinition
+	Replicas      []*StoreTarget
+	CoreResources mapset.Set
 }
 
 func (config *StorageConfig) AgentInit() (tree.Agent, error) {
@@ -22,6 +25,8 @@ func (config *StorageConfig) AgentInit() (tree.Agent, error) {
 	agent := &CoreStoreAgent{
 		Client:        config.Client,
 		DirDefinition: config.DirDefinition,
+		Replicas:      config.Replicas,
+		CoreResources: config.CoreResources,
 	}
 	return agent, nil
 }
@@ -0,0 +1,251 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const uploadFilePrefix = ".upload-"
+
+type FileSystemStoreClient struct {
+	RootDir string
+	Bucket  string
+}
+
+func (client *FileSystemStoreClient) ListBucket() ([]string, error) {
+	entries, err := os.ReadDir(client.RootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			buckets = append(buckets, entry.Name())
+		}
+	}
+	return buckets, nil
+}
+
+func (client *FileSystemStoreClient) ListObjects(prefix string) ([]string, error) {
+	keys, err := client.walkKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, key)
+		}
+	}
+	return objects, nil
+}
+
+// ListCommonPrefix follows the delimiter semantics of object storages: every key which starts with
+// the prefix and contains the delimiter after it is rolled up into the prefix up to the first delimiter.
+func (client *FileSystemStoreClient) ListCommonPrefix(prefix, delimiter string) ([]string, error) {
+	if len(delimiter) == 0 {
+		return nil, nil
+	}
+
+	objects, err := client.ListObjects(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var commonPrefixes []string
+	seen := map[string]bool{}
+	for _, key := range objects {
+		idx := strings.Index(key[len(prefix):], delimiter)
+		if idx < 0 {
+			continue
+		}
+		commonPrefix := key[:len(prefix)+idx+len(delimiter)]
+		if !seen[commonPrefix] {
+			seen[commonPrefix] = true
+			commonPrefixes = append(commonPrefixes, commonPrefix)
+		}
+	}
+	return commonPrefixes, nil
+}
+
+func (client *FileSystemStoreClient) CreateBucket(bucketName string) error {
+	bucketDir, err := client.getPath(client.RootDir, bucketName)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(bucketDir, os.ModePerm)
+}
+
+func (client *FileSystemStoreClient) GetObject(key string) (io.ReadCloser, error) {
+	objectFile, err := client.getObjectPath(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(objectFile)
+}
+
+// UploadObject writes the object to a temporary file first and renames it afterwards,
+// so a partially written object is never visible under its key.
+func (client *FileSystemStoreClient) UploadObject(key string, body io.Reader, size int64) error {
+	objectFile, err := client.getObjectPath(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(objectFile), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	uploadFile, err := os.CreateTemp(filepath.Dir(objectFile), uploadFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(uploadFile.Name())
+	}()
+
+	written, err := io.Copy(uploadFile, body)
+	if closeErr := uploadFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if size >= 0 && written != size {
+		return fmt.Errorf("uploaded %d bytes but the object size is %d", written, size)
+	}
+
+	return os.Rename(uploadFile.Name(), objectFile)
+}
+
+func (client *FileSystemStoreClient) DeleteObject(key string) error {
+	objectFile, err := client.getObjectPath(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(objectFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	client.removeEmptyDirs(filepath.Dir(objectFile))
+	return nil
+}
+
+func (client *FileSystemStoreClient) DeletePrefix(prefix string) error {
+	if len(prefix) == 0 {
+		return errors.New("can not delete objects with an empty prefix")
+	}
+
+	objects, err := client.ListObjects(prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range objects {
+		err = client.DeleteObject(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close has nothing to release, the files are closed by every call.
+func (client *FileSystemStoreClient) Close() error {
+	return nil
+}
+
+func (client *FileSystemStoreClient) StoragePluginHealthCheck() error {
+	stat, err := os.Stat(client.bucketDir())
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("bucket %s is not a directory", client.Bucket)
+	}
+
+	checkFile, err := os.CreateTemp(client.bucketDir(), uploadFilePrefix+"health-*")
+	if err != nil {
+		return err
+	}
+	err = checkFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(checkFile.Name())
+}
+
+func (client *FileSystemStoreClient) bucketDir() string {
+	return filepath.Join(client.RootDir, client.Bucket)
+}
+
+func (client *FileSystemStoreClient) getObjectPath(key string) (string, error) {
+	if len(key) == 0 || strings.HasSuffix(key, "/") {
+		return "", fmt.Errorf("invalid object key: %q", key)
+	}
+	return client.getPath(client.bucketDir(), key)
+}
+
+// getPath joins a slash separated key to a directory and refuses keys which escape it.
+func (client *FileSystemStoreClient) getPath(dir, key string) (string, error) {
+	cleanKey := path.Clean("/" + key)
+	if cleanKey == "/" || cleanKey != "/"+key {
+		return "", fmt.Errorf("invalid key: %q", key)
+	}
+	return filepath.Join(dir, filepath.FromSlash(cleanKey)), nil
+}
+
+// walkKeys lists every object of the bucket by its key in lexical order.
+func (client *FileSystemStoreClient) walkKeys() ([]string, error) {
+	bucketDir := client.bucketDir()
+
+	var keys []string
+	err := filepath.WalkDir(bucketDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && file == bucketDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), uploadFilePrefix) {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(bucketDir, file)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(relativePath))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// removeEmptyDirs removes the directories which are left empty by a deleted object, up to the bucket.
+func (client *FileSystemStoreClient) removeEmptyDirs(dir string) {
+	bucketDir := client.bucketDir()
+	for dir != bucketDir && strings.HasPrefix(dir, bucketDir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
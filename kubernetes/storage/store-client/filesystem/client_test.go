@@ -0,0 +1,122 @@
+package filesystem
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func uploadString(t *testing.T, client *FileSystemStoreClient, key, body string) {
+	if err := client.UploadObject(key, strings.NewReader(body), int64(len(body))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadAndGetObject(t *testing.T) {
+	config := &FileSystemConfig{RootDir: t.TempDir(), Bucket: "boxroom"}
+	if _, err := config.ClientInit(); err == nil {
+		t.Fatal("expected a missing bucket error")
+	}
+	if err := os.Mkdir(filepath.Join(config.RootDir, config.Bucket), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	storeClient, err := config.ClientInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := storeClient.(*FileSystemStoreClient)
+	uploadString(t, client, "root/backup/tree/json.tar.gz", "content")
+
+	reader, err := client.GetObject("root/backup/tree/json.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "content" {
+		t.Fatalf("unexpected object body %q", body)
+	}
+
+	if err = client.UploadObject("root/short", strings.NewReader("abc"), 4); err == nil {
+		t.Fatal("expected a size mismatch error")
+	}
+	if _, err = client.GetObject("../escape"); err == nil {
+		t.Fatal("expected an invalid key error")
+	}
+}
+
+func TestListCommonPrefix(t *testing.T) {
+	config := &FileSystemConfig{RootDir: t.TempDir(), Bucket: "boxroom"}
+	if err := os.Mkdir(filepath.Join(config.RootDir, config.Bucket), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	storeClient, err := config.ClientInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := storeClient.(*FileSystemStoreClient)
+	uploadString(t, client, "root/backup/tree-a/json.tar.gz", "a")
+	uploadString(t, client, "root/backup/tree-a/yaml.tar.gz", "a")
+	uploadString(t, client, "root/backup/tree-b/json.tar.gz", "b")
+	uploadString(t, client, "root/restore/tree-c/json.tar.gz", "c")
+	uploadString(t, client, "root/backup.log", "log")
+
+	prefixes, err := client.ListCommonPrefix("root/backup/", "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"root/backup/tree-a/", "root/backup/tree-b/"}
+	if !reflect.DeepEqual(prefixes, expected) {
+		t.Fatalf("expected %v, got %v", expected, prefixes)
+	}
+
+	prefixes, err = client.ListCommonPrefix("root/", "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"root/backup/", "root/restore/"}
+	if !reflect.DeepEqual(prefixes, expected) {
+		t.Fatalf("expected %v, got %v", expected, prefixes)
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	config := &FileSystemConfig{RootDir: t.TempDir(), Bucket: "boxroom"}
+	if err := os.Mkdir(filepath.Join(config.RootDir, config.Bucket), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	storeClient, err := config.ClientInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := storeClient.(*FileSystemStoreClient)
+	uploadString(t, client, "root/backup/tree-a/json.tar.gz", "a")
+	uploadString(t, client, "root/backup/tree-ab/json.tar.gz", "ab")
+
+	if err := client.DeletePrefix("root/backup/tree-a/"); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := client.ListObjects("root/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"root/backup/tree-ab/json.tar.gz"}
+	if !reflect.DeepEqual(objects, expected) {
+		t.Fatalf("expected %v, got %v", expected, objects)
+	}
+
+	if err = client.DeleteObject("root/backup/tree-a/json.tar.gz"); err != nil {
+		t.Fatalf("deleting a missing object should succeed: %v", err)
+	}
+	if err = client.StoragePluginHealthCheck(); err != nil {
+		t.Fatal(err)
+	}
+}
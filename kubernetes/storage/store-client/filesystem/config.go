@@ -0,0 +1,56 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
+	util "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
+	"os"
+	"path/filepath"
+)
+
+var log = new(util.NewLog).GetLogger()
+
+// FileSystemConfig keeps trees under a local directory or a mounted volume, every bucket is a directory
+// below RootDir and every object is a file whose path below the bucket is its key.
+type FileSystemConfig struct {
+	RootDir string
+	Bucket  string
+}
+
+func (config *FileSystemConfig) ClientInit() (storeclient.StoreClient, error) {
+	if len(config.RootDir) == 0 || len(config.Bucket) == 0 {
+		return nil, errors.New("filesystem storage needs a root dir and a bucket")
+	}
+
+	rootDir, err := filepath.Abs(config.RootDir)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	// the bucket is created along with the storage, a missing directory is reported instead of created
+	// so a wrong root dir or an unmounted volume does not look like an empty storage
+	stat, err := os.Stat(filepath.Join(rootDir, config.Bucket))
+	if err != nil {
+		log.Error(err)
+		return nil, fmt.Errorf("bucket %s does not exist under %s: %w", config.Bucket, rootDir, err)
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("bucket %s under %s is not a directory", config.Bucket, rootDir)
+	}
+
+	storeClient := &FileSystemStoreClient{
+		RootDir: rootDir,
+		Bucket:  config.Bucket,
+	}
+
+	return storeClient, nil
+}
+
+// the storage url of a filesystem storage location is the root dir, usually the mount path of a volume
+func init() {
+	storeclient.RegisterStoreClient(storeclient.FileSystemClientKind, func(settings *storeclient.StoreClientSettings) (storeclient.StoreClientConfig, error) {
+		return &FileSystemConfig{RootDir: settings.StorageUrl, Bucket: settings.Bucket}, nil
+	})
+}
@@ -2,17 +2,24 @@ package store_client
 
 import (
 	"io"
-	"os"
 )
 
+// ChunkSize is the largest piece of an object which is sent in a single call to a storage plugin,
+// objects are streamed chunk by chunk so they never have to be kept in memory as a whole.
+const ChunkSize = 8 * 1024 * 1024
+
 type StoreClient interface {
 	ListBucket() ([]string, error)
 	ListObjects(prefix string) ([]string, error)
 	ListCommonPrefix(prefix, delimiter string) ([]string, error)
 	CreateBucket(bucketName string) error
-	GetObject(key string) (io.Reader, error)
-	UploadObject(fileName string, body *os.File) error
+	GetObject(key string) (io.ReadCloser, error)
+	UploadObject(key string, body io.Reader, size int64) error
+	DeleteObject(key string) error
+	DeletePrefix(prefix string) error
 	StoragePluginHealthCheck() error
+	// Close releases the connection of the client, it is called once the client is no longer used.
+	Close() error
 }
 
 type StoreClientConfig interface {
@@ -35,6 +42,8 @@ type CreateBucketInput struct {
 	BucketName string
 }
 
+// GetObjectInput and UploadObjectInput belong to the whole object calls of the plugins
+// which do not support the chunked calls yet.
 type GetObjectInput struct {
 	Key string
 }
@@ -44,6 +53,39 @@ type UploadObjectInput struct {
 	Body     []byte
 }
 
+type GetObjectChunkInput struct {
+	Key    string
+	Offset int64
+	Length int64
+}
+
+type CreateObjectUploadInput struct {
+	Key  string
+	Size int64
+}
+
+type UploadObjectChunkInput struct {
+	UploadId    string
+	ChunkNumber int
+	Body        []byte
+}
+
+type CompleteObjectUploadInput struct {
+	UploadId string
+}
+
+type AbortObjectUploadInput struct {
+	UploadId string
+}
+
+type DeleteObjectInput struct {
+	Key string
+}
+
+type DeletePrefixInput struct {
+	Prefix string
+}
+
 type StoragePluginHealthCheckInput struct {
 	CheckMethods string
 }
@@ -72,6 +114,35 @@ type UploadObjectOutput struct {
 	UploadObjectStatus bool
 }
 
+type GetObjectChunkOutput struct {
+	Body []byte
+	EOF  bool
+}
+
+type CreateObjectUploadOutput struct {
+	UploadId string
+}
+
+type UploadObjectChunkOutput struct {
+	UploadObjectChunkStatus bool
+}
+
+type CompleteObjectUploadOutput struct {
+	UploadObjectStatus bool
+}
+
+type AbortObjectUploadOutput struct {
+	AbortObjectUploadStatus bool
+}
+
+type DeleteObjectOutput struct {
+	DeleteObjectStatus bool
+}
+
+type DeletePrefixOutput struct {
+	DeletedObjects []string
+}
+
 type StoragePluginHealthCheckOutput struct {
 	HealthStatus bool
 }
@@ -0,0 +1,81 @@
+package store_client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	RpcPluginClientKind  = "rpc-plugin"
+	FileSystemClientKind = "filesystem"
+	S3ClientKind         = "s3"
+
+	// DefaultClientKind keeps storage locations which do not choose a client on the storage plugin pods.
+	DefaultClientKind = RpcPluginClientKind
+)
+
+// StoreClientSettings is everything a storage location knows about its storage, every client kind picks the settings it needs.
+type StoreClientSettings struct {
+	StoragePluginUrl string
+	StorageUrl       string
+	Region           string
+	Bucket           string
+	AccessKey        string
+	SecretKey        string
+	DisableSSL       bool
+	S3ForcePathStyle bool
+}
+
+// StoreClientFactory builds the config of a store client from the settings of a storage location.
+type StoreClientFactory func(settings *StoreClientSettings) (StoreClientConfig, error)
+
+var (
+	storeClientFactories     = map[string]StoreClientFactory{}
+	storeClientFactoriesLock sync.RWMutex
+)
+
+// RegisterStoreClient registers the factory of a client kind, it is meant to be called from the init of an implementation.
+func RegisterStoreClient(clientKind string, factory StoreClientFactory) {
+	storeClientFactoriesLock.Lock()
+	defer storeClientFactoriesLock.Unlock()
+
+	if _, exist := storeClientFactories[clientKind]; exist {
+		panic(fmt.Sprintf("store client of client kind %s is registered twice", clientKind))
+	}
+	storeClientFactories[clientKind] = factory
+}
+
+// GetStoreClientKinds lists the registered client kinds.
+func GetStoreClientKinds() []string {
+	storeClientFactoriesLock.RLock()
+	defer storeClientFactoriesLock.RUnlock()
+
+	kinds := make([]string, 0, len(storeClientFactories))
+	for kind := range storeClientFactories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewStoreClient builds a ready store client of the client kind, an empty kind falls back to DefaultClientKind.
+func NewStoreClient(clientKind string, settings *StoreClientSettings) (StoreClient, error) {
+	if len(clientKind) == 0 {
+		clientKind = DefaultClientKind
+	}
+
+	storeClientFactoriesLock.RLock()
+	factory, exist := storeClientFactories[clientKind]
+	storeClientFactoriesLock.RUnlock()
+	if !exist {
+		return nil, fmt.Errorf("unknown client kind %s, registered client kinds are: %s", clientKind, strings.Join(GetStoreClientKinds(), ", "))
+	}
+
+	config, err := factory(settings)
+	if err != nil {
+		return nil, err
+	}
+	return config.ClientInit()
+}
@@ -1,15 +1,32 @@
 package awss3
 
 import (
-	storeclient "boxroom/storage/store-client"
 	"bytes"
+	"errors"
+	"fmt"
+	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
 	"io"
 	"net/rpc"
-	"os"
+	"strings"
 )
 
 type BoxroomStoreClient struct {
 	RpcClient *rpc.Client
+	// wholeObjects is set once the plugin turns out not to support the chunked calls,
+	// objects are then sent as a whole with S3Client.GetObject and S3Client.UploadObject.
+	wholeObjects bool
+}
+
+// isMethodNotFound tells whether the plugin does not implement the called method, which is the case
+// for plugins built against an older version of the rpc protocol.
+func isMethodNotFound(err error) bool {
+	serverErr, ok := err.(rpc.ServerError)
+	return ok && strings.Contains(string(serverErr), "can't find method")
+}
+
+// Close closes the connection to the storage plugin.
+func (client *BoxroomStoreClient) Close() error {
+	return client.RpcClient.Close()
 }
 
 func (client *BoxroomStoreClient) ListBucket() ([]string, error) {
@@ -59,7 +76,31 @@ func (client *BoxroomStoreClient) CreateBucket(bucketName string) error {
 	return nil
 }
 
-func (client *BoxroomStoreClient) GetObject(key string) (io.Reader, error) {
+func (client *BoxroomStoreClient) GetObject(key string) (io.ReadCloser, error) {
+	if client.wholeObjects {
+		return client.getWholeObject(key)
+	}
+
+	reader := &chunkReader{
+		client: client,
+		key:    key,
+	}
+
+	// the first chunk is fetched at once so that a missing object is reported here instead of on the first read
+	err := reader.fetchChunk()
+	if isMethodNotFound(err) {
+		log.Infof("storage plugin does not support S3Client.GetObjectChunk, objects are sent as a whole")
+		client.wholeObjects = true
+		return client.getWholeObject(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+func (client *BoxroomStoreClient) getWholeObject(key string) (io.ReadCloser, error) {
 	input := &storeclient.GetObjectInput{
 		Key: key,
 	}
@@ -68,12 +109,54 @@ func (client *BoxroomStoreClient) GetObject(key string) (io.Reader, error) {
 	if err != nil {
 		return nil, err
 	}
-	reader := bytes.NewReader(output.File)
+	return io.NopCloser(bytes.NewReader(output.File)), nil
+}
 
-	return reader, nil
+func (client *BoxroomStoreClient) UploadObject(key string, body io.Reader, size int64) error {
+	if client.wholeObjects {
+		return client.uploadWholeObject(key, body)
+	}
+
+	createInput := &storeclient.CreateObjectUploadInput{
+		Key:  key,
+		Size: size,
+	}
+	createOutput := &storeclient.CreateObjectUploadOutput{}
+	err := client.RpcClient.Call("S3Client.CreateObjectUpload", createInput, createOutput)
+	if isMethodNotFound(err) {
+		log.Infof("storage plugin does not support S3Client.CreateObjectUpload, objects are sent as a whole")
+		client.wholeObjects = true
+		return client.uploadWholeObject(key, body)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = client.uploadChunks(createOutput.UploadId, body, size)
+	if err != nil {
+		abortInput := &storeclient.AbortObjectUploadInput{
+			UploadId: createOutput.UploadId,
+		}
+		abortErr := client.RpcClient.Call("S3Client.AbortObjectUpload", abortInput, &storeclient.AbortObjectUploadOutput{})
+		if abortErr != nil {
+			log.Error(abortErr)
+		}
+		return err
+	}
+
+	completeInput := &storeclient.CompleteObjectUploadInput{
+		UploadId: createOutput.UploadId,
+	}
+	completeOutput := &storeclient.CompleteObjectUploadOutput{}
+	err = client.RpcClient.Call("S3Client.CompleteObjectUpload", completeInput, completeOutput)
+	if err != nil {
+		return err
+	}
+	return nil
 }
 
-func (client *BoxroomStoreClient) UploadObject(fileName string, body *os.File) error {
+// uploadWholeObject keeps the object in memory, it is only used for plugins which do not support the chunked calls.
+func (client *BoxroomStoreClient) uploadWholeObject(key string, body io.Reader) error {
 	buffer := bytes.Buffer{}
 	_, err := buffer.ReadFrom(body)
 	if err != nil {
@@ -81,7 +164,7 @@ func (client *BoxroomStoreClient) UploadObject(fileName string, body *os.File) e
 	}
 
 	input := &storeclient.UploadObjectInput{
-		FileName: fileName,
+		FileName: key,
 		Body:     buffer.Bytes(),
 	}
 	output := &storeclient.UploadObjectOutput{}
@@ -92,6 +175,140 @@ func (client *BoxroomStoreClient) UploadObject(fileName string, body *os.File) e
 	return nil
 }
 
+func (client *BoxroomStoreClient) uploadChunks(uploadId string, body io.Reader, size int64) error {
+	buffer := make([]byte, storeclient.ChunkSize)
+	var uploaded int64
+
+	for chunkNumber := 1; ; chunkNumber++ {
+		n, err := io.ReadFull(body, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if n == 0 && chunkNumber > 1 {
+			break
+		}
+
+		input := &storeclient.UploadObjectChunkInput{
+			UploadId:    uploadId,
+			ChunkNumber: chunkNumber,
+			Body:        buffer[:n],
+		}
+		output := &storeclient.UploadObjectChunkOutput{}
+		callErr := client.RpcClient.Call("S3Client.UploadObjectChunk", input, output)
+		if callErr != nil {
+			return callErr
+		}
+		uploaded += int64(n)
+
+		if err != nil {
+			break
+		}
+	}
+
+	if size >= 0 && uploaded != size {
+		return fmt.Errorf("uploaded %d bytes but the object size is %d", uploaded, size)
+	}
+	return nil
+}
+
+func (client *BoxroomStoreClient) DeleteObject(key string) error {
+	input := &storeclient.DeleteObjectInput{
+		Key: key,
+	}
+	output := &storeclient.DeleteObjectOutput{}
+	err := client.RpcClient.Call("S3Client.DeleteObject", input, output)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (client *BoxroomStoreClient) DeletePrefix(prefix string) error {
+	if len(prefix) == 0 {
+		return errors.New("can not delete objects with an empty prefix")
+	}
+
+	input := &storeclient.DeletePrefixInput{
+		Prefix: prefix,
+	}
+	output := &storeclient.DeletePrefixOutput{}
+	err := client.RpcClient.Call("S3Client.DeletePrefix", input, output)
+	if err == nil {
+		return nil
+	}
+
+	// older plugins can only delete single objects, so the prefix is deleted object by object
+	if !isMethodNotFound(err) {
+		return err
+	}
+	log.Infof("storage plugin does not support S3Client.DeletePrefix, delete objects one by one: prefix: %s", prefix)
+
+	objects, err := client.ListObjects(prefix)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		err = client.DeleteObject(object)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type chunkReader struct {
+	client *BoxroomStoreClient
+	key    string
+	offset int64
+	buffer []byte
+	eof    bool
+	closed bool
+}
+
+func (reader *chunkReader) Read(p []byte) (int, error) {
+	if reader.closed {
+		return 0, errors.New("read from a closed object reader")
+	}
+
+	for len(reader.buffer) == 0 {
+		if reader.eof {
+			return 0, io.EOF
+		}
+		err := reader.fetchChunk()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, reader.buffer)
+	reader.buffer = reader.buffer[n:]
+	return n, nil
+}
+
+func (reader *chunkReader) Close() error {
+	reader.closed = true
+	reader.buffer = nil
+	return nil
+}
+
+func (reader *chunkReader) fetchChunk() error {
+	input := &storeclient.GetObjectChunkInput{
+		Key:    reader.key,
+		Offset: reader.offset,
+		Length: storeclient.ChunkSize,
+	}
+	output := &storeclient.GetObjectChunkOutput{}
+	err := reader.client.RpcClient.Call("S3Client.GetObjectChunk", input, output)
+	if err != nil {
+		return err
+	}
+
+	reader.buffer = output.Body
+	reader.offset += int64(len(output.Body))
+	reader.eof = output.EOF || len(output.Body) == 0
+	return nil
+}
+
 func (client *BoxroomStoreClient) StoragePluginHealthCheck() error {
 	input := &storeclient.StoragePluginHealthCheckInput{
 		CheckMethods: "ListBucket",
@@ -0,0 +1,224 @@
+package awss3
+
+import (
+	"bytes"
+	"errors"
+	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
+	"io"
+	"net"
+	"net/http"
+	"net/rpc"
+	"strconv"
+	"testing"
+)
+
+// chunkedPlugin is a storage plugin which speaks the chunked rpc protocol and keeps the objects in memory.
+type chunkedPlugin struct {
+	objects     map[string][]byte
+	uploads     map[string]*bytes.Buffer
+	chunkCalls  int
+	abortedKeys int
+}
+
+func (plugin *chunkedPlugin) GetObjectChunk(input *storeclient.GetObjectChunkInput, output *storeclient.GetObjectChunkOutput) error {
+	plugin.chunkCalls++
+	object, ok := plugin.objects[input.Key]
+	if !ok {
+		return errors.New("NoSuchKey: " + input.Key)
+	}
+
+	end := input.Offset + input.Length
+	if end >= int64(len(object)) {
+		end = int64(len(object))
+		output.EOF = true
+	}
+	output.Body = object[input.Offset:end]
+	return nil
+}
+
+func (plugin *chunkedPlugin) CreateObjectUpload(input *storeclient.CreateObjectUploadInput, output *storeclient.CreateObjectUploadOutput) error {
+	output.UploadId = input.Key
+	plugin.uploads[input.Key] = &bytes.Buffer{}
+	return nil
+}
+
+func (plugin *chunkedPlugin) UploadObjectChunk(input *storeclient.UploadObjectChunkInput, output *storeclient.UploadObjectChunkOutput) error {
+	plugin.chunkCalls++
+	plugin.uploads[input.UploadId].Write(input.Body)
+	output.UploadObjectChunkStatus = true
+	return nil
+}
+
+func (plugin *chunkedPlugin) CompleteObjectUpload(input *storeclient.CompleteObjectUploadInput, output *storeclient.CompleteObjectUploadOutput) error {
+	plugin.objects[input.UploadId] = plugin.uploads[input.UploadId].Bytes()
+	delete(plugin.uploads, input.UploadId)
+	output.UploadObjectStatus = true
+	return nil
+}
+
+func (plugin *chunkedPlugin) AbortObjectUpload(input *storeclient.AbortObjectUploadInput, output *storeclient.AbortObjectUploadOutput) error {
+	plugin.abortedKeys++
+	delete(plugin.uploads, input.UploadId)
+	output.AbortObjectUploadStatus = true
+	return nil
+}
+
+// wholeObjectPlugin is a storage plugin which only knows the whole object calls of the older rpc protocol.
+type wholeObjectPlugin struct {
+	objects map[string][]byte
+}
+
+func (plugin *wholeObjectPlugin) GetObject(input *storeclient.GetObjectInput, output *storeclient.GetObjectOutput) error {
+	object, ok := plugin.objects[input.Key]
+	if !ok {
+		return errors.New("NoSuchKey: " + input.Key)
+	}
+	output.File = object
+	return nil
+}
+
+func (plugin *wholeObjectPlugin) UploadObject(input *storeclient.UploadObjectInput, output *storeclient.UploadObjectOutput) error {
+	plugin.objects[input.FileName] = input.Body
+	output.UploadObjectStatus = true
+	return nil
+}
+
+// servePlugin serves the plugin as S3Client over an in-memory connection and returns a client of it.
+func servePlugin(t *testing.T, plugin interface{}) *BoxroomStoreClient {
+	server := rpc.NewServer()
+	if err := server.RegisterName("S3Client", plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	client := &BoxroomStoreClient{RpcClient: rpc.NewClient(clientConn)}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestChunkReader(t *testing.T) {
+	object := bytes.Repeat([]byte("boxroom"), 2*storeclient.ChunkSize/7+10)
+	plugin := &chunkedPlugin{objects: map[string][]byte{"tree/json.tar.gz": object}}
+	client := servePlugin(t, plugin)
+
+	reader, err := client.GetObject("tree/json.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, object) {
+		t.Fatalf("read %d bytes which differ from the object of %d bytes", len(body), len(object))
+	}
+	if plugin.chunkCalls != 3 {
+		t.Errorf("object was read in %d chunks, want 3", plugin.chunkCalls)
+	}
+
+	if err = reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reader.Read(make([]byte, 1)); err == nil {
+		t.Error("read from a closed reader succeeded")
+	}
+
+	if _, err = client.GetObject("tree/missing"); err == nil {
+		t.Error("expected an error for a missing object")
+	}
+}
+
+func TestUploadChunks(t *testing.T) {
+	plugin := &chunkedPlugin{objects: map[string][]byte{}, uploads: map[string]*bytes.Buffer{}}
+	client := servePlugin(t, plugin)
+
+	object := bytes.Repeat([]byte("boxroom"), 2*storeclient.ChunkSize/7+10)
+	if err := client.UploadObject("tree/json.tar.gz", bytes.NewReader(object), int64(len(object))); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plugin.objects["tree/json.tar.gz"], object) {
+		t.Fatalf("uploaded object of %d bytes differs from the object of %d bytes", len(plugin.objects["tree/json.tar.gz"]), len(object))
+	}
+	if plugin.chunkCalls != 3 {
+		t.Errorf("object was uploaded in %d chunks, want 3", plugin.chunkCalls)
+	}
+
+	if err := client.UploadObject("tree/empty", bytes.NewReader(nil), 0); err != nil {
+		t.Fatal(err)
+	}
+	if object, ok := plugin.objects["tree/empty"]; !ok || len(object) != 0 {
+		t.Errorf("empty object was not uploaded: %q", object)
+	}
+
+	if err := client.UploadObject("tree/short", bytes.NewReader([]byte("abc")), 4); err == nil {
+		t.Error("expected a size mismatch error")
+	}
+	if _, ok := plugin.objects["tree/short"]; ok || plugin.abortedKeys != 1 {
+		t.Errorf("upload with a size mismatch was not aborted")
+	}
+}
+
+func TestWholeObjectFallback(t *testing.T) {
+	plugin := &wholeObjectPlugin{objects: map[string][]byte{}}
+	client := servePlugin(t, plugin)
+
+	for i := 0; i < 2; i++ {
+		key := "tree/object-" + strconv.Itoa(i)
+		if err := client.UploadObject(key, bytes.NewReader([]byte(key)), int64(len(key))); err != nil {
+			t.Fatal(err)
+		}
+
+		reader, err := client.GetObject(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != key {
+			t.Errorf("read %q, want %q", body, key)
+		}
+	}
+	if !client.wholeObjects {
+		t.Error("client has not switched to whole objects")
+	}
+
+	// a download is the first call of a new client as well when a backup is restored
+	reader, err := servePlugin(t, plugin).GetObject("tree/object-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(reader); string(body) != "tree/object-0" {
+		t.Errorf("read %q, want %q", body, "tree/object-0")
+	}
+}
+
+func TestDialHTTP(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("S3Client", &wholeObjectPlugin{objects: map[string][]byte{"tree/json.tar.gz": []byte("tree")}}); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() { _ = http.Serve(listener, server) }()
+
+	rpcClient, err := dialHTTP(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rpcClient.Close()
+
+	output := &storeclient.GetObjectOutput{}
+	if err = rpcClient.Call("S3Client.GetObject", &storeclient.GetObjectInput{Key: "tree/json.tar.gz"}, output); err != nil {
+		t.Fatal(err)
+	}
+	if string(output.File) != "tree" {
+		t.Errorf("read %q, want %q", output.File, "tree")
+	}
+}
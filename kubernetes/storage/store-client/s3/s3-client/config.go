@@ -1,11 +1,25 @@
 package awss3
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
 	util "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
+	"io"
+	"net"
+	"net/http"
 	"net/rpc"
+	"time"
 )
 
+// dialTimeout bounds connecting to the storage plugin and the http handshake of the rpc connection,
+// so an unreachable plugin does not block the reconcile.
+const dialTimeout = 10 * time.Second
+
+// connected is the status which the rpc server of the plugin answers the CONNECT of the handshake with.
+const connected = "200 Connected to Go RPC"
+
 var log = new(util.NewLog).GetLogger()
 
 type S3Config struct {
@@ -13,7 +27,7 @@ type S3Config struct {
 }
 
 func (config *S3Config) ClientInit() (storeclient.StoreClient, error) {
-	rpcClient, err := rpc.DialHTTP("tcp", config.StoragePluginUrl)
+	rpcClient, err := dialHTTP(config.StoragePluginUrl)
 	if err != nil {
 		log.Error(err)
 		return nil, err
@@ -25,3 +39,41 @@ func (config *S3Config) ClientInit() (storeclient.StoreClient, error) {
 
 	return storeClient, nil
 }
+
+// dialHTTP connects to the rpc server of the plugin like rpc.DialHTTP, but gives up after dialTimeout.
+func dialHTTP(address string) (*rpc.Client, error) {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.SetDeadline(time.Now().Add(dialTimeout))
+	if err == nil {
+		_, err = io.WriteString(conn, "CONNECT "+rpc.DefaultRPCPath+" HTTP/1.0\n\n")
+	}
+	if err == nil {
+		var resp *http.Response
+		resp, err = http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+		if err == nil && resp.Status != connected {
+			err = fmt.Errorf("unexpected http response: %s", resp.Status)
+		}
+	}
+	if err == nil {
+		err = conn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("can not connect to the storage plugin %s: %w", address, err)
+	}
+
+	return rpc.NewClient(conn), nil
+}
+
+func init() {
+	storeclient.RegisterStoreClient(storeclient.RpcPluginClientKind, func(settings *storeclient.StoreClientSettings) (storeclient.StoreClientConfig, error) {
+		if len(settings.StoragePluginUrl) == 0 {
+			return nil, errors.New("the service of the storage plugin is not ready")
+		}
+		return &S3Config{StoragePluginUrl: settings.StoragePluginUrl}, nil
+	})
+}
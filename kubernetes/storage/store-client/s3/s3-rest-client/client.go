@@ -0,0 +1,345 @@
+package s3rest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type S3RestStoreClient struct {
+	Endpoint   *url.URL
+	Region     string
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	PathStyle  bool
+	HttpClient *http.Client
+	partSize   int
+}
+
+// S3Error is the error document returned by the storage for a failed request.
+type S3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	Resource   string `xml:"Resource"`
+}
+
+func (err *S3Error) Error() string {
+	if len(err.Code) == 0 {
+		return fmt.Sprintf("s3 request failed with status %d", err.StatusCode)
+	}
+	return fmt.Sprintf("s3 request failed with status %d: %s: %s", err.StatusCode, err.Code, err.Message)
+}
+
+type listAllMyBucketsResult struct {
+	Buckets []struct {
+		Name string `xml:"Name"`
+	} `xml:"Buckets>Bucket"`
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+type createBucketConfiguration struct {
+	XMLName            xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CreateBucketConfiguration"`
+	LocationConstraint string   `xml:"LocationConstraint"`
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+func (client *S3RestStoreClient) ListBucket() ([]string, error) {
+	result := &listAllMyBucketsResult{}
+	err := client.doXml(http.MethodGet, "", "", nil, nil, result)
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []string
+	for _, bucket := range result.Buckets {
+		buckets = append(buckets, bucket.Name)
+	}
+	return buckets, nil
+}
+
+func (client *S3RestStoreClient) ListObjects(prefix string) ([]string, error) {
+	objects, _, err := client.listObjectsV2(prefix, "")
+	return objects, err
+}
+
+func (client *S3RestStoreClient) ListCommonPrefix(prefix, delimiter string) ([]string, error) {
+	_, commonPrefixes, err := client.listObjectsV2(prefix, delimiter)
+	return commonPrefixes, err
+}
+
+func (client *S3RestStoreClient) CreateBucket(bucketName string) error {
+	var body []byte
+	if client.Region != defaultRegion {
+		configuration, err := xml.Marshal(&createBucketConfiguration{LocationConstraint: client.Region})
+		if err != nil {
+			return err
+		}
+		body = configuration
+	}
+
+	err := client.doXml(http.MethodPut, bucketName, "", nil, body, nil)
+	var s3Err *S3Error
+	if errors.As(err, &s3Err) && s3Err.Code == "BucketAlreadyOwnedByYou" {
+		return nil
+	}
+	return err
+}
+
+func (client *S3RestStoreClient) GetObject(key string) (io.ReadCloser, error) {
+	resp, err := client.do(http.MethodGet, client.Bucket, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// UploadObject puts objects up to the part size in one request and switches to a multipart upload
+// for bigger ones, so an object is never held in memory as a whole.
+func (client *S3RestStoreClient) UploadObject(key string, body io.Reader, size int64) error {
+	buffer := make([]byte, client.partSize)
+	n, err := io.ReadFull(body, buffer)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if size >= 0 && int64(n) != size {
+			return fmt.Errorf("uploaded %d bytes but the object size is %d", n, size)
+		}
+		return client.doXml(http.MethodPut, client.Bucket, key, nil, buffer[:n], nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := &initiateMultipartUploadResult{}
+	err = client.doXml(http.MethodPost, client.Bucket, key, url.Values{"uploads": {""}}, nil, result)
+	if err != nil {
+		return err
+	}
+
+	err = client.uploadParts(key, result.UploadId, body, buffer, size)
+	if err != nil {
+		abortErr := client.doXml(http.MethodDelete, client.Bucket, key, url.Values{"uploadId": {result.UploadId}}, nil, nil)
+		if abortErr != nil {
+			log.Error(abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (client *S3RestStoreClient) DeleteObject(key string) error {
+	err := client.doXml(http.MethodDelete, client.Bucket, key, nil, nil, nil)
+	var s3Err *S3Error
+	if errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func (client *S3RestStoreClient) DeletePrefix(prefix string) error {
+	if len(prefix) == 0 {
+		return errors.New("can not delete objects with an empty prefix")
+	}
+
+	objects, err := client.ListObjects(prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range objects {
+		err = client.DeleteObject(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the idle connections of the http client.
+func (client *S3RestStoreClient) Close() error {
+	client.HttpClient.CloseIdleConnections()
+	return nil
+}
+
+func (client *S3RestStoreClient) StoragePluginHealthCheck() error {
+	return client.doXml(http.MethodHead, client.Bucket, "", nil, nil, nil)
+}
+
+func (client *S3RestStoreClient) listObjectsV2(prefix, delimiter string) ([]string, []string, error) {
+	var objects, commonPrefixes []string
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if len(delimiter) != 0 {
+			query.Set("delimiter", delimiter)
+		}
+		if len(continuationToken) != 0 {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		result := &listBucketResult{}
+		err := client.doXml(http.MethodGet, client.Bucket, "", query, nil, result)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, content.Key)
+		}
+		for _, commonPrefix := range result.CommonPrefixes {
+			commonPrefixes = append(commonPrefixes, commonPrefix.Prefix)
+		}
+
+		if !result.IsTruncated || len(result.NextContinuationToken) == 0 {
+			return objects, commonPrefixes, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// uploadParts uploads the first part which is already in the buffer and every following part of the body,
+// then completes the multipart upload.
+func (client *S3RestStoreClient) uploadParts(key, uploadId string, body io.Reader, buffer []byte, size int64) error {
+	var parts []completedPart
+	var uploaded int64
+	part := buffer
+	for partNumber := 1; ; partNumber++ {
+		query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadId}}
+		resp, err := client.do(http.MethodPut, client.Bucket, key, query, part)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+
+		parts = append(parts, completedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+		uploaded += int64(len(part))
+
+		n, err := io.ReadFull(body, buffer)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		part = buffer[:n]
+	}
+
+	if size >= 0 && uploaded != size {
+		return fmt.Errorf("uploaded %d bytes but the object size is %d", uploaded, size)
+	}
+
+	completeBody, err := xml.Marshal(&completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	return client.doXml(http.MethodPost, client.Bucket, key, url.Values{"uploadId": {uploadId}}, completeBody, nil)
+}
+
+// doXml sends the request and decodes the response document into result. A storage may answer
+// with an error document even though the status is ok, so the document is checked for it.
+func (client *S3RestStoreClient) doXml(method, bucket, key string, query url.Values, body []byte, result interface{}) error {
+	resp, err := client.do(method, bucket, key, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(respBody) == 0 {
+		return nil
+	}
+
+	s3Err := &S3Error{StatusCode: resp.StatusCode}
+	document := struct {
+		XMLName xml.Name
+	}{}
+	if xml.Unmarshal(respBody, &document) == nil && document.XMLName.Local == "Error" {
+		_ = xml.Unmarshal(respBody, s3Err)
+		return s3Err
+	}
+
+	if result == nil {
+		return nil
+	}
+	return xml.Unmarshal(respBody, result)
+}
+
+func (client *S3RestStoreClient) do(method, bucket, key string, query url.Values, body []byte) (*http.Response, error) {
+	req, err := client.newRequest(method, bucket, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		s3Err := &S3Error{StatusCode: resp.StatusCode}
+		respBody, _ := io.ReadAll(resp.Body)
+		_ = xml.Unmarshal(respBody, s3Err)
+		return nil, s3Err
+	}
+	return resp, nil
+}
+
+func (client *S3RestStoreClient) newRequest(method, bucket, key string, query url.Values, body []byte) (*http.Request, error) {
+	requestUrl := *client.Endpoint
+	switch {
+	case len(bucket) == 0:
+		requestUrl.Path += "/"
+	case client.PathStyle:
+		requestUrl.Path += "/" + bucket + "/" + key
+	default:
+		requestUrl.Host = bucket + "." + requestUrl.Host
+		requestUrl.Path += "/" + key
+	}
+	requestUrl.RawPath = encodePath(requestUrl.Path)
+	requestUrl.RawQuery = getCanonicalQuery(query)
+
+	req, err := http.NewRequest(method, requestUrl.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set(amzContentSha256, hashHex(body))
+
+	if len(client.AccessKey) != 0 {
+		signRequest(req, client.AccessKey, client.SecretKey, client.Region, time.Now())
+	}
+	return req, nil
+}
@@ -0,0 +1,118 @@
+package s3rest
+
+import (
+	"errors"
+	"fmt"
+	storeclient "github.io/misskaori/boxroom-crd/kubernetes/storage/store-client"
+	util "github.io/misskaori/boxroom-crd/kubernetes/util/util-log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var log = new(util.NewLog).GetLogger()
+
+const defaultRegion = "us-east-1"
+
+// The timeouts bound connecting to the storage and waiting for its answer, so a stalled endpoint does not block
+// the reconciles. There is no overall timeout because the body of a large object is streamed for a long time.
+const (
+	dialTimeout           = 10 * time.Second
+	responseHeaderTimeout = 30 * time.Second
+)
+
+// S3RestConfig talks to an S3 compatible storage directly from the controller instead of going
+// through the storage plugin pods. The fields mirror the config of a StorageLocations.
+type S3RestConfig struct {
+	StorageUrl       string
+	Region           string
+	Bucket           string
+	AccessKey        string
+	SecretKey        string
+	DisableSSL       bool
+	S3ForcePathStyle bool
+}
+
+func (config *S3RestConfig) ClientInit() (storeclient.StoreClient, error) {
+	if len(config.Bucket) == 0 {
+		return nil, errors.New("s3 storage needs a bucket")
+	}
+
+	region := config.Region
+	if len(region) == 0 {
+		region = defaultRegion
+	}
+
+	endpoint, err := config.getEndpoint(region)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	storeClient := &S3RestStoreClient{
+		Endpoint:   endpoint,
+		Region:     region,
+		Bucket:     config.Bucket,
+		AccessKey:  config.AccessKey,
+		SecretKey:  config.SecretKey,
+		PathStyle:  config.S3ForcePathStyle,
+		HttpClient: newHttpClient(),
+		partSize:   storeclient.ChunkSize,
+	}
+
+	return storeClient, nil
+}
+
+func newHttpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = dialTimeout
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	return &http.Client{Transport: transport}
+}
+
+// getEndpoint resolves the storage url, an url without a scheme uses https unless ssl is disabled,
+// an empty url points to aws s3 of the region.
+func (config *S3RestConfig) getEndpoint(region string) (*url.URL, error) {
+	scheme := "https"
+	if config.DisableSSL {
+		scheme = "http"
+	}
+
+	storageUrl := config.StorageUrl
+	if len(storageUrl) == 0 {
+		storageUrl = fmt.Sprintf("s3.%s.amazonaws.com", region)
+	}
+	if !strings.Contains(storageUrl, "://") {
+		storageUrl = scheme + "://" + storageUrl
+	}
+
+	endpoint, err := url.Parse(storageUrl)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoint.Host) == 0 {
+		return nil, fmt.Errorf("invalid storage url: %s", config.StorageUrl)
+	}
+	if config.DisableSSL {
+		endpoint.Scheme = "http"
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/")
+	return endpoint, nil
+}
+
+func init() {
+	storeclient.RegisterStoreClient(storeclient.S3ClientKind, func(settings *storeclient.StoreClientSettings) (storeclient.StoreClientConfig, error) {
+		return &S3RestConfig{
+			StorageUrl:       settings.StorageUrl,
+			Region:           settings.Region,
+			Bucket:           settings.Bucket,
+			AccessKey:        settings.AccessKey,
+			SecretKey:        settings.SecretKey,
+			DisableSSL:       settings.DisableSSL,
+			S3ForcePathStyle: settings.S3ForcePathStyle,
+		}, nil
+	})
+}
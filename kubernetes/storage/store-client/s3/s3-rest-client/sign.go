@@ -0,0 +1,126 @@
+package s3rest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	signAlgorithm    = "AWS4-HMAC-SHA256"
+	signService      = "s3"
+	amzDateFormat    = "20060102T150405Z"
+	amzShortFormat   = "20060102"
+	amzDateHeader    = "X-Amz-Date"
+	amzContentSha256 = "X-Amz-Content-Sha256"
+)
+
+// signRequest signs the request with AWS signature version 4, the host and every header which is set
+// on the request at this point are signed. The payload hash has to be set on the request already.
+func signRequest(req *http.Request, accessKey, secretKey, region string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	req.Header.Set(amzDateHeader, amzDate)
+
+	signedHeaders, canonicalHeaders := getCanonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		encodePath(req.URL.Path),
+		getCanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		req.Header.Get(amzContentSha256),
+	}, "\n")
+
+	scope := strings.Join([]string{now.UTC().Format(amzShortFormat), region, signService, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{signAlgorithm, amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+secretKey), now.UTC().Format(amzShortFormat))
+	signingKey = hmacSha256(signingKey, region)
+	signingKey = hmacSha256(signingKey, signService)
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signAlgorithm, accessKey, scope, signedHeaders, signature))
+}
+
+func getCanonicalHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		name := strings.ToLower(key)
+		if name == "authorization" || name == "user-agent" {
+			continue
+		}
+		trimmed := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), canonicalHeaders.String()
+}
+
+func getCanonicalQuery(query url.Values) string {
+	params := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			params = append(params, encodeQuery(key)+"="+encodeQuery(value))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// encodePath encodes every byte of the path except the unreserved characters and slashes.
+func encodePath(path string) string {
+	if len(path) == 0 {
+		return "/"
+	}
+	return uriEncode(path, false)
+}
+
+func encodeQuery(value string) string {
+	return uriEncode(value, true)
+}
+
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			encoded.WriteByte(c)
+			continue
+		}
+		encoded.WriteString(fmt.Sprintf("%%%02X", c))
+	}
+	return encoded.String()
+}
+
+func hashHex(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}